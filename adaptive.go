@@ -0,0 +1,128 @@
+package cache
+
+import "math"
+
+const (
+	// Number of requests in each hit rate sample relative to the capacity.
+	hillClimberSampleMultiplier = 10
+	// Ratio of the capacity the window is resized in each step.
+	hillClimberStepPercent = 0.0625
+	// Step size decay rate when the hit rate is stable.
+	hillClimberStepDecayRate = 0.98
+	// Hit rate change which restarts the step size.
+	hillClimberRestartThreshold = 0.05
+)
+
+// adaptiveTinyLFU is TinyLFU which admission window and main space are
+// resized periodically by hill climbing the sampled hit rate.
+// See https://dl.acm.org/doi/10.1145/3274808.3274816
+type adaptiveTinyLFU struct {
+	tinyLFU
+
+	cap        int
+	sampleSize int
+	hits       int
+	misses     int
+
+	previousHitRate float64
+	// stepSize is the number of entries the window is resized by in the next
+	// adjustment. Positive number increases the window size.
+	stepSize float64
+}
+
+func (l *adaptiveTinyLFU) init(c *cache, cap int) {
+	l.tinyLFU.init(c, cap)
+	l.cap = cap
+	if cap > 1 && l.lru.cap < 1 {
+		// Window must not be empty to be adjusted.
+		l.setWindowCap(1)
+	}
+	l.sampleSize = hillClimberSampleMultiplier * cap
+	l.stepSize = -hillClimberStepPercent * float64(cap)
+}
+
+func (l *adaptiveTinyLFU) write(en *entry) *entry {
+	if en.accessList == nil {
+		l.misses++
+	}
+	ren := l.tinyLFU.write(en)
+	l.climb()
+	return ren
+}
+
+func (l *adaptiveTinyLFU) access(en *entry) {
+	if en.accessList == nil {
+		// Already removed.
+		return
+	}
+	l.hits++
+	l.tinyLFU.access(en)
+	l.climb()
+}
+
+// climb adjusts the window size when a sample of requests is collected.
+func (l *adaptiveTinyLFU) climb() {
+	total := l.hits + l.misses
+	if l.cap <= 1 || total < l.sampleSize {
+		return
+	}
+	hitRate := float64(l.hits) / float64(total)
+	change := hitRate - l.previousHitRate
+	amount := l.stepSize
+	if change < 0 {
+		// Hit rate decreased, go in the opposite direction.
+		amount = -amount
+	}
+	if math.Abs(change) >= hillClimberRestartThreshold {
+		l.stepSize = math.Copysign(hillClimberStepPercent*float64(l.cap), amount)
+	} else {
+		l.stepSize = hillClimberStepDecayRate * amount
+	}
+	l.previousHitRate = hitRate
+	l.hits = 0
+	l.misses = 0
+
+	l.setWindowCap(l.lru.cap + int(amount))
+}
+
+// setWindowCap resizes the window and main space, then moves entries between
+// them so that they are within their capacity.
+func (l *adaptiveTinyLFU) setWindowCap(n int) {
+	if n < 1 {
+		n = 1
+	}
+	if n > l.cap-1 {
+		n = l.cap - 1
+	}
+	if n == l.lru.cap {
+		return
+	}
+	l.lru.cap = n
+	mainCap := l.cap - n
+	l.slru.protectedCap = int(float64(mainCap) * protectedRatio)
+	l.slru.probationCap = mainCap - l.slru.protectedCap
+
+	// Window shrunk, move its least recently used entries to the main space.
+	for l.lru.ls.Len() > l.lru.cap {
+		en := getEntry(l.lru.ls.Back())
+		l.lru.ls.Remove(en.accessList)
+		en.listID = probationSegment
+		en.accessList = l.slru.probationLs.PushFront(en)
+	}
+	// Main space shrunk, move its victims to the window.
+	for l.slru.length() > mainCap {
+		var en *entry
+		if el := l.slru.probationLs.Back(); el != nil {
+			en = getEntry(el)
+			l.slru.probationLs.Remove(el)
+		} else {
+			en = getEntry(l.slru.protectedLs.Back())
+			l.slru.protectedLs.Remove(en.accessList)
+		}
+		en.listID = admissionWindow
+		en.accessList = l.lru.ls.PushFront(en)
+	}
+	for l.slru.protectedLs.Len() > l.slru.protectedCap {
+		l.slru.demote()
+	}
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// replayPolicy requests keys through the policy and returns number of hits.
+func replayPolicy(p policy, keys []int) int {
+	c := cache{}
+	c.init(defaultConcurrencyLevel)
+	p.init(&c, 100)
+	hits := 0
+	for _, k := range keys {
+		h := sum(k)
+		en := c.get(k, h)
+		if en != nil {
+			p.access(en)
+			hits++
+		} else {
+			p.write(newEntry(k, k, h))
+		}
+	}
+	return hits
+}
+
+func TestAdaptiveTinyLFU(t *testing.T) {
+	c := cache{}
+	c.init(defaultConcurrencyLevel)
+	l := adaptiveTinyLFU{}
+	l.init(&c, 100)
+	if l.lru.cap != 1 {
+		t.Fatalf("unexpected window capacity: %d", l.lru.cap)
+	}
+	for i := 0; i < 100; i++ {
+		l.write(newEntry(i, i, sum(i)))
+	}
+	assertLen := func() {
+		t.Helper()
+		if l.lru.ls.Len() > l.lru.cap || l.slru.protectedLs.Len() > l.slru.protectedCap ||
+			l.lru.cap+l.slru.protectedCap+l.slru.probationCap != 100 ||
+			l.lru.ls.Len()+l.slru.length() != 100 || cacheSize(&c) != 100 {
+			t.Fatalf("unexpected length: window=%d/%d protected=%d/%d probation=%d/%d",
+				l.lru.ls.Len(), l.lru.cap, l.slru.protectedLs.Len(), l.slru.protectedCap,
+				l.slru.probationLs.Len(), l.slru.probationCap)
+		}
+	}
+	for i := 50; i < 100; i++ {
+		l.access(c.get(i, sum(i)))
+	}
+	assertLen()
+	l.setWindowCap(60)
+	assertLen()
+	l.setWindowCap(10)
+	assertLen()
+	l.setWindowCap(0)
+	if l.lru.cap != 1 {
+		t.Fatalf("unexpected window capacity: %d", l.lru.cap)
+	}
+	assertLen()
+}
+
+func TestAdaptiveTinyLFURecency(t *testing.T) {
+	// Half of requests are for new keys, the others are for keys requested
+	// recently, which favors a large window.
+	r := rand.New(rand.NewSource(1))
+	var keys []int
+	n := 0
+	for i := 0; i < 50000; i++ {
+		if n < 200 || r.Intn(2) == 0 {
+			keys = append(keys, n)
+			n++
+		} else {
+			keys = append(keys, n-1-r.Intn(200))
+		}
+	}
+	fixed := replayPolicy(&tinyLFU{}, keys)
+	l := &adaptiveTinyLFU{}
+	adaptive := replayPolicy(l, keys)
+	if adaptive <= fixed {
+		t.Fatalf("unexpected hits: adaptive=%d tinylfu=%d", adaptive, fixed)
+	}
+	if l.lru.cap <= 50 {
+		t.Fatalf("unexpected window capacity: %d", l.lru.cap)
+	}
+}
+
+func TestAdaptiveTinyLFUFrequency(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	z := rand.NewZipf(r, 1.01, 1, 10000)
+	keys := make([]int, 50000)
+	for i := range keys {
+		keys[i] = int(z.Uint64())
+	}
+	fixed := replayPolicy(&tinyLFU{}, keys)
+	adaptive := replayPolicy(&adaptiveTinyLFU{}, keys)
+	// Window may be a little larger than optimal due to sampling noise.
+	if adaptive < fixed*95/100 {
+		t.Fatalf("unexpected hits: adaptive=%d tinylfu=%d", adaptive, fixed)
+	}
+}
@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"container/list"
+)
+
+const (
+	arcRecent uint8 = iota
+	arcFrequent
+)
+
+// ghostList is a LRU list of hashes of evicted entries.
+type ghostList struct {
+	ls list.List
+	m  map[uint64]*list.Element
+}
+
+func (g *ghostList) init() {
+	g.ls.Init()
+	g.m = make(map[uint64]*list.Element)
+}
+
+func (g *ghostList) len() int {
+	return g.ls.Len()
+}
+
+func (g *ghostList) contains(h uint64) bool {
+	_, ok := g.m[h]
+	return ok
+}
+
+// add adds hash h to the front of the list.
+func (g *ghostList) add(h uint64) {
+	if el, ok := g.m[h]; ok {
+		g.ls.MoveToFront(el)
+		return
+	}
+	g.m[h] = g.ls.PushFront(h)
+}
+
+// remove removes hash h and returns true if it was in the list.
+func (g *ghostList) remove(h uint64) bool {
+	el, ok := g.m[h]
+	if !ok {
+		return false
+	}
+	g.ls.Remove(el)
+	delete(g.m, h)
+	return true
+}
+
+// removeBack removes the least recently added hash.
+func (g *ghostList) removeBack() {
+	if el := g.ls.Back(); el != nil {
+		g.ls.Remove(el)
+		delete(g.m, el.Value.(uint64))
+	}
+}
+
+// arcCache is an Adaptive Replacement Cache.
+// Resident entries are in either the recent (T1) or frequent (T2) list.
+// Hashes of evicted entries are kept in ghost lists B1 and B2 which are used
+// to adapt target size of the recent list.
+// See https://www.usenix.org/legacy/events/fast03/tech/megiddo.html
+type arcCache struct {
+	cache *cache
+	cap   int
+	// p is the target size of the recent list.
+	p int
+
+	t1 list.List
+	t2 list.List
+	b1 ghostList
+	b2 ghostList
+}
+
+// init initializes the cache lists.
+func (l *arcCache) init(c *cache, cap int) {
+	l.cache = c
+	l.cap = cap
+	l.p = 0
+	l.t1.Init()
+	l.t2.Init()
+	l.b1.init()
+	l.b2.init()
+}
+
+// length returns number of resident entries.
+func (l *arcCache) length() int {
+	return l.t1.Len() + l.t2.Len()
+}
+
+// write adds new entry to the cache and returns evicted entry if necessary.
+func (l *arcCache) write(en *entry) *entry {
+	// Fast path
+	if en.accessList != nil {
+		// Entry existed, update its status instead.
+		l.markAccess(en)
+		return nil
+	}
+	cen := l.cache.getOrSet(en)
+	if cen != nil {
+		// Entry has already been added, update its value instead.
+		cen.setValue(en.getValue())
+		cen.setWriteTime(en.getWriteTime())
+		if cen.accessList != nil {
+			l.markAccess(cen)
+			return nil
+		}
+		// Entry is loaded to the cache but not yet registered.
+		en = cen
+	}
+	if l.cap <= 0 {
+		// Unbounded cache does not need ghost entries.
+		en.listID = arcRecent
+		en.accessList = l.t1.PushFront(en)
+		return nil
+	}
+	var ren *entry
+	switch {
+	case l.b1.contains(en.hash):
+		// Recent list should have been larger.
+		delta := 1
+		if l.b1.len() < l.b2.len() {
+			delta = l.b2.len() / l.b1.len()
+		}
+		l.p += delta
+		if l.p > l.cap {
+			l.p = l.cap
+		}
+		ren = l.replace(false)
+		l.b1.remove(en.hash)
+		en.listID = arcFrequent
+		en.accessList = l.t2.PushFront(en)
+	case l.b2.contains(en.hash):
+		// Frequent list should have been larger.
+		delta := 1
+		if l.b2.len() < l.b1.len() {
+			delta = l.b1.len() / l.b2.len()
+		}
+		l.p -= delta
+		if l.p < 0 {
+			l.p = 0
+		}
+		ren = l.replace(true)
+		l.b2.remove(en.hash)
+		en.listID = arcFrequent
+		en.accessList = l.t2.PushFront(en)
+	default:
+		if l.t1.Len()+l.b1.len() >= l.cap {
+			if l.t1.Len() < l.cap {
+				l.b1.removeBack()
+				ren = l.replace(false)
+			} else {
+				// Recent list is full, evict without remembering it.
+				ren = l.removeBack(&l.t1)
+			}
+		} else if total := l.length() + l.b1.len() + l.b2.len(); total >= l.cap {
+			if total >= 2*l.cap {
+				l.b2.removeBack()
+			}
+			ren = l.replace(false)
+		}
+		en.listID = arcRecent
+		en.accessList = l.t1.PushFront(en)
+	}
+	return ren
+}
+
+// replace evicts an entry from either the recent or frequent list to its
+// ghost list when the cache is full.
+func (l *arcCache) replace(inB2 bool) *entry {
+	if l.length() < l.cap {
+		// Entries were removed explicitly.
+		return nil
+	}
+	t1 := l.t1.Len()
+	if t1 > 0 && (t1 > l.p || (inB2 && t1 == l.p)) {
+		en := l.removeBack(&l.t1)
+		l.b1.add(en.hash)
+		return en
+	}
+	en := l.removeBack(&l.t2)
+	if en != nil {
+		l.b2.add(en.hash)
+	}
+	return en
+}
+
+// removeBack removes the last entry of ls and returns it.
+func (l *arcCache) removeBack(ls *list.List) *entry {
+	el := ls.Back()
+	if el == nil {
+		return nil
+	}
+	return l.remove(getEntry(el))
+}
+
+// access updates cache entry for a get.
+func (l *arcCache) access(en *entry) {
+	if en.accessList != nil {
+		l.markAccess(en)
+	}
+}
+
+// markAccess moves the entry to the front of the frequent list.
+// en.accessList must not be null.
+func (l *arcCache) markAccess(en *entry) {
+	if en.listID == arcFrequent {
+		l.t2.MoveToFront(en.accessList)
+		return
+	}
+	l.t1.Remove(en.accessList)
+	en.listID = arcFrequent
+	en.accessList = l.t2.PushFront(en)
+}
+
+// remove removes an entry from the cache and returns the removed entry or nil
+// if it is not found.
+func (l *arcCache) remove(en *entry) *entry {
+	if en.accessList == nil {
+		return nil
+	}
+	l.cache.delete(en)
+	if en.listID == arcFrequent {
+		l.t2.Remove(en.accessList)
+	} else {
+		l.t1.Remove(en.accessList)
+	}
+	en.accessList = nil
+	return en
+}
+
+// iterate walks through all lists by access time.
+func (l *arcCache) iterate(fn func(en *entry) bool) {
+	iterateListFromBack(&l.t2, fn)
+	iterateListFromBack(&l.t1, fn)
+}
@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"testing"
+)
+
+type arcTest struct {
+	c   cache
+	arc arcCache
+	t   *testing.T
+}
+
+func (t *arcTest) assertLen(t1, t2, b1, b2 int) {
+	sz := cacheSize(&t.c)
+	if sz != t1+t2 || t.arc.t1.Len() != t1 || t.arc.t2.Len() != t2 ||
+		t.arc.b1.len() != b1 || t.arc.b2.len() != b2 {
+		t.t.Helper()
+		t.t.Fatalf("unexpected data length: cache=%d t1=%d t2=%d b1=%d b2=%d, want: %d %d %d %d",
+			sz, t.arc.t1.Len(), t.arc.t2.Len(), t.arc.b1.len(), t.arc.b2.len(), t1, t2, b1, b2)
+	}
+}
+
+func (t *arcTest) assertEntry(k int, id uint8) {
+	en := t.c.get(k, sum(k))
+	if en == nil || en.listID != id {
+		t.t.Helper()
+		t.t.Fatalf("unexpected entry: %+v, want: {key: %v, listID: %v}", en, k, id)
+	}
+}
+
+func (t *arcTest) assertRemoved(en *entry, k int) {
+	if en == nil || en.key != k {
+		t.t.Helper()
+		t.t.Fatalf("unexpected entry removed: %+v, want: %v", en, k)
+	}
+}
+
+func (t *arcTest) write(k int) *entry {
+	return t.arc.write(newEntry(k, k, sum(k)))
+}
+
+func TestARC(t *testing.T) {
+	s := arcTest{t: t}
+	s.c.init(defaultConcurrencyLevel)
+	s.arc.init(&s.c, 4)
+
+	for i := 0; i < 4; i++ {
+		if en := s.write(i); en != nil {
+			t.Fatalf("unexpected entry removed: %+v", en)
+		}
+	}
+	// t1: 3 2 1 0
+	s.assertLen(4, 0, 0, 0)
+	s.arc.access(s.c.get(0, sum(0)))
+	s.arc.access(s.c.get(1, sum(1)))
+	// t1: 3 2, t2: 1 0
+	s.assertLen(2, 2, 0, 0)
+	s.assertEntry(0, arcFrequent)
+	s.assertEntry(3, arcRecent)
+
+	s.assertRemoved(s.write(4), 2)
+	// t1: 4 3, t2: 1 0, b1: 2
+	s.assertLen(2, 2, 1, 0)
+
+	// Hit in b1 increases target size of t1.
+	s.assertRemoved(s.write(2), 3)
+	// t1: 4, t2: 2 1 0, b1: 3
+	s.assertLen(1, 3, 1, 0)
+	s.assertEntry(2, arcFrequent)
+	if s.arc.p != 1 {
+		t.Fatalf("unexpected target size: %d", s.arc.p)
+	}
+
+	s.assertRemoved(s.write(5), 0)
+	// t1: 5 4, t2: 2 1, b1: 3, b2: 0
+	s.assertLen(2, 2, 1, 1)
+
+	// Hit in b2 decreases target size of t1.
+	s.assertRemoved(s.write(0), 4)
+	// t1: 5, t2: 0 2 1, b1: 4 3
+	s.assertLen(1, 3, 2, 0)
+	if s.arc.p != 0 {
+		t.Fatalf("unexpected target size: %d", s.arc.p)
+	}
+
+	s.assertRemoved(s.arc.remove(s.c.get(1, sum(1))), 1)
+	s.assertLen(1, 2, 2, 0)
+	// Cache is not full.
+	if en := s.write(6); en != nil {
+		t.Fatalf("unexpected entry removed: %+v", en)
+	}
+	// t1: 6 5, t2: 0 2, b1: 4 3
+	s.assertLen(2, 2, 2, 0)
+
+	var keys []Key
+	s.arc.iterate(func(en *entry) bool {
+		keys = append(keys, en.key)
+		return true
+	})
+	if len(keys) != 4 || keys[0] != 2 || keys[1] != 0 || keys[2] != 5 || keys[3] != 6 {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+}
+
+func TestARCScan(t *testing.T) {
+	// Frequently used keys should survive a scan.
+	var keys []int
+	for i := 0; i < 100; i++ {
+		for k := 0; k < 50; k++ {
+			keys = append(keys, k, k)
+		}
+		for k := 0; k < 60; k++ {
+			keys = append(keys, 1000+i*100+k)
+		}
+	}
+	arc := replayPolicy(&arcCache{}, keys)
+	lru := replayPolicy(&lruCache{}, keys)
+	if arc <= lru {
+		t.Fatalf("unexpected hits: arc=%d lru=%d", arc, lru)
+	}
+}
@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	// Number of entries in each read buffer.
+	readBufferSize = 16
+	readBufferMask = readBufferSize - 1
+	// Maximum number of read buffers.
+	maximumReadBuffers = 64
+)
+
+// readBuffer is a lossy bounded buffer of accessed entries. It has multiple
+// producers and a single consumer. Entries are dropped when the buffer is
+// full or contended, so producers never block.
+type readBuffer struct {
+	head uint32 // Only changed by the consumer, access atomically
+	tail uint32 // Access atomically
+	buf  [readBufferSize]unsafe.Pointer
+}
+
+// offer adds the entry to the buffer and returns true if the buffer is full
+// and should be drained. The entry may be dropped.
+func (b *readBuffer) offer(en *entry) bool {
+	head := atomic.LoadUint32(&b.head)
+	tail := atomic.LoadUint32(&b.tail)
+	size := tail - head
+	if size >= readBufferSize {
+		return true
+	}
+	if atomic.CompareAndSwapUint32(&b.tail, tail, tail+1) {
+		atomic.StorePointer(&b.buf[tail&readBufferMask], unsafe.Pointer(en))
+		return size+1 >= readBufferSize
+	}
+	// Contended, drop this entry.
+	return false
+}
+
+// drain calls fn for all entries in the buffer and returns number of entries drained.
+// This function must only be called by the consumer.
+func (b *readBuffer) drain(fn func(*entry)) int {
+	head := atomic.LoadUint32(&b.head)
+	tail := atomic.LoadUint32(&b.tail)
+	n := 0
+	for ; head != tail; head++ {
+		idx := head & readBufferMask
+		p := atomic.LoadPointer(&b.buf[idx])
+		if p == nil {
+			// The producer has not stored the entry yet.
+			break
+		}
+		atomic.StorePointer(&b.buf[idx], nil)
+		fn((*entry)(p))
+		n++
+	}
+	atomic.StoreUint32(&b.head, head)
+	return n
+}
+
+// hashBuffer is a lossy bounded buffer of key hashes like readBuffer.
+// Zero marks empty slots, so a zero hash is stored as one.
+type hashBuffer struct {
+	head uint32 // Only changed by the consumer, access atomically
+	tail uint32 // Access atomically
+	buf  [readBufferSize]uint64
+}
+
+// offer adds h to the buffer and returns true if the buffer is full and
+// should be drained. The hash may be dropped.
+func (b *hashBuffer) offer(h uint64) bool {
+	if h == 0 {
+		h = 1
+	}
+	head := atomic.LoadUint32(&b.head)
+	tail := atomic.LoadUint32(&b.tail)
+	size := tail - head
+	if size >= readBufferSize {
+		return true
+	}
+	if atomic.CompareAndSwapUint32(&b.tail, tail, tail+1) {
+		atomic.StoreUint64(&b.buf[tail&readBufferMask], h)
+		return size+1 >= readBufferSize
+	}
+	// Contended, drop this hash.
+	return false
+}
+
+// drain calls fn for all hashes in the buffer and returns number of hashes drained.
+// This function must only be called by the consumer.
+func (b *hashBuffer) drain(fn func(uint64)) int {
+	head := atomic.LoadUint32(&b.head)
+	tail := atomic.LoadUint32(&b.tail)
+	n := 0
+	for ; head != tail; head++ {
+		idx := head & readBufferMask
+		h := atomic.LoadUint64(&b.buf[idx])
+		if h == 0 {
+			// The producer has not stored the hash yet.
+			break
+		}
+		atomic.StoreUint64(&b.buf[idx], 0)
+		fn(h)
+		n++
+	}
+	atomic.StoreUint32(&b.head, head)
+	return n
+}
+
+// nextPowerOfTwo returns the smallest power of two which is greater than or equal to i.
+func nextPowerOfTwo(i uint32) uint32 {
+	n := i - 1
+	n |= n >> 1
+	n |= n >> 2
+	n |= n >> 4
+	n |= n >> 8
+	n |= n >> 16
+	n++
+	return n
+}
+
+// stripedBuffer is a set of read buffers which are selected by entry hash
+// to reduce contention.
+type stripedBuffer struct {
+	buffers []readBuffer
+	mask    uint64
+}
+
+func (s *stripedBuffer) init() {
+	n := nextPowerOfTwo(uint32(4 * runtime.GOMAXPROCS(0)))
+	if n > maximumReadBuffers {
+		n = maximumReadBuffers
+	}
+	s.buffers = make([]readBuffer, n)
+	s.mask = uint64(n - 1)
+}
+
+// offer adds entry to its buffer and returns true if that buffer should be drained.
+func (s *stripedBuffer) offer(en *entry) bool {
+	return s.buffers[spread(en.hash)&s.mask].offer(en)
+}
+
+// drain drains all buffers and returns total number of entries drained.
+func (s *stripedBuffer) drain(fn func(*entry)) int {
+	n := 0
+	for i := range s.buffers {
+		n += s.buffers[i].drain(fn)
+	}
+	return n
+}
+
+// stripedHashBuffer is a set of hash buffers which are selected by hash.
+type stripedHashBuffer struct {
+	buffers []hashBuffer
+	mask    uint64
+}
+
+func (s *stripedHashBuffer) init() {
+	n := nextPowerOfTwo(uint32(4 * runtime.GOMAXPROCS(0)))
+	if n > maximumReadBuffers {
+		n = maximumReadBuffers
+	}
+	s.buffers = make([]hashBuffer, n)
+	s.mask = uint64(n - 1)
+}
+
+// offer adds h to its buffer and returns true if that buffer should be drained.
+func (s *stripedHashBuffer) offer(h uint64) bool {
+	return s.buffers[spread(h)&s.mask].offer(h)
+}
+
+// drain drains all buffers and returns total number of hashes drained.
+func (s *stripedHashBuffer) drain(fn func(uint64)) int {
+	n := 0
+	for i := range s.buffers {
+		n += s.buffers[i].drain(fn)
+	}
+	return n
+}
@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadBuffer(t *testing.T) {
+	b := readBuffer{}
+	en := make([]*entry, readBufferSize+1)
+	for i := range en {
+		en[i] = newEntry(i, i, uint64(i))
+	}
+	for i := 0; i < readBufferSize-1; i++ {
+		if b.offer(en[i]) {
+			t.Fatalf("unexpected full buffer at %d", i)
+		}
+	}
+	if !b.offer(en[readBufferSize-1]) {
+		t.Fatalf("expect full buffer")
+	}
+	// Dropped
+	if !b.offer(en[readBufferSize]) {
+		t.Fatalf("expect full buffer")
+	}
+	var drained []*entry
+	n := b.drain(func(e *entry) {
+		drained = append(drained, e)
+	})
+	if n != readBufferSize || len(drained) != readBufferSize {
+		t.Fatalf("unexpected drained: %d %d", n, len(drained))
+	}
+	for i, e := range drained {
+		if e != en[i] {
+			t.Fatalf("unexpected entry: %+v, want: %+v", e, en[i])
+		}
+	}
+	if b.offer(en[0]) {
+		t.Fatalf("unexpected full buffer")
+	}
+	n = b.drain(func(*entry) {})
+	if n != 1 {
+		t.Fatalf("unexpected drained: %d", n)
+	}
+}
+
+// accessPolicy notifies added and accessed entries.
+type accessPolicy struct {
+	priorityPolicy
+	added    chan Key
+	accessed chan Key
+}
+
+func (p *accessPolicy) Add(en *Entry) *Entry {
+	ren := p.priorityPolicy.Add(en)
+	p.added <- en.Key()
+	return ren
+}
+
+func (p *accessPolicy) Access(en *Entry) {
+	p.priorityPolicy.Access(en)
+	p.accessed <- en.Key()
+}
+
+func TestReadBufferPeriodicDrain(t *testing.T) {
+	p := &accessPolicy{added: make(chan Key, 1), accessed: make(chan Key, 1)}
+	c := New(WithCustomPolicy(p))
+	defer c.Close()
+	c.Put(1, 1)
+	<-p.added
+	// A single read does not fill the buffer.
+	c.GetIfPresent(1)
+	select {
+	case k := <-p.accessed:
+		if k != 1 {
+			t.Fatalf("unexpected accessed key: %v", k)
+		}
+	case <-time.After(5 * drainInterval):
+		t.Fatal("access is not drained")
+	}
+}
+
+func TestGetIfPresentNonBlocking(t *testing.T) {
+	removing := make(chan struct{})
+	unblock := make(chan struct{})
+	remFunc := func(Key, Value) {
+		select {
+		case removing <- struct{}{}:
+			<-unblock
+		default:
+		}
+	}
+	c := New(WithMaximumSize(1), WithRemovalListener(remFunc))
+	defer c.Close()
+
+	c.Put(1, 1)
+	c.Put(2, 2)
+	// processEntries is now blocked by the removal listener.
+	<-removing
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10*chanBufSize; i++ {
+			c.GetIfPresent(2)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("GetIfPresent is blocked")
+	}
+	close(unblock)
+}
+
+func TestGetIfPresentExpiredNonBlocking(t *testing.T) {
+	mockTime := newMockTime()
+	currentTime = mockTime.now
+	defer func() {
+		currentTime = time.Now
+	}()
+	removing := make(chan struct{})
+	unblock := make(chan struct{})
+	remFunc := func(Key, Value) {
+		select {
+		case removing <- struct{}{}:
+			<-unblock
+		default:
+		}
+	}
+	c := New(WithMaximumSize(2), WithExpireAfterWrite(time.Minute),
+		WithRemovalListener(remFunc))
+	defer c.Close()
+	defer close(unblock)
+
+	c.Put(1, 1)
+	c.Put(2, 2)
+	c.Put(3, 3)
+	// processEntries is now blocked by the removal listener.
+	<-removing
+	mockTime.add(2 * time.Minute)
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10*chanBufSize; i++ {
+			for k := 1; k <= 3; k++ {
+				if _, ok := c.GetIfPresent(k); ok {
+					t.Errorf("unexpected hit: %v", k)
+				}
+			}
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("GetIfPresent is blocked")
+	}
+}
@@ -53,6 +53,9 @@ type LoadingCache interface {
 	Refresh(Key)
 }
 
+// Weigher returns the relative size of the given entry. It must be positive.
+type Weigher func(Key, Value) int
+
 // LoaderFunc retrieves the value corresponding to given Key.
 type LoaderFunc func(Key) (Value, error)
 
@@ -0,0 +1,209 @@
+// Command cachesim replays cache traces through cache policies and reports
+// hit rate, byte hit rate, evictions and throughput of each policy and cache size.
+// With -analyze, it reports characteristics of the traces instead.
+//
+// Usage:
+//
+//	cachesim -format cache2k -policies lru,tinylfu -sizes 1000,2000 traces/oltp.trace.gz
+//	cachesim -format cache2k -analyze traces/oltp.trace.gz
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goburrow/cache/traces"
+)
+
+type result struct {
+	Policy      string  `json:"policy"`
+	CacheSize   int     `json:"cacheSize"`
+	Requests    uint64  `json:"requests"`
+	Hits        uint64  `json:"hits"`
+	HitRate     float64 `json:"hitRate"`
+	ByteHitRate float64 `json:"byteHitRate"`
+	Evictions   uint64  `json:"evictions"`
+	Duration    float64 `json:"duration"` // in seconds
+	Throughput  float64 `json:"throughput"`
+}
+
+func main() {
+	format := flag.String("format", "", "trace format: "+strings.Join(traces.Formats, ", "))
+	policies := flag.String("policies", strings.Join(traces.Policies(), ","), "comma-separated cache policies")
+	sizes := flag.String("sizes", "1000", "comma-separated cache sizes in number of entries")
+	output := flag.String("output", "", "output format: csv (default) or json, text (default) or json with -analyze")
+	max := flag.Int("max", 0, "maximum number of requests to replay, 0 for all")
+	analyze := flag.Bool("analyze", false, "analyze traces instead of simulating policies")
+	window := flag.Int("window", 10000, "number of requests in each working set window of -analyze")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] file...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var err error
+	if *analyze {
+		err = runAnalyze(*format, flag.Args(), *output, *max, *window, os.Stdout)
+	} else {
+		err = run(*format, flag.Args(), split(*policies), *sizes, *output, *max, os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(format string, patterns []string, policies []string, sizes string, output string, max int, w io.Writer) error {
+	var cacheSizes []int
+	for _, s := range split(sizes) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid cache size %s", s)
+		}
+		cacheSizes = append(cacheSizes, n)
+	}
+	if output == "" {
+		output = "csv"
+	}
+	if output != "csv" && output != "json" {
+		return fmt.Errorf("unsupported output format %s", output)
+	}
+	r, err := openFiles(patterns)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var results []result
+	for _, policy := range policies {
+		for _, size := range cacheSizes {
+			if err = r.Reset(); err != nil {
+				return err
+			}
+			p, err := traces.NewProvider(format, r)
+			if err != nil {
+				return err
+			}
+			res, err := simulate(p, policy, size, max)
+			if err != nil {
+				return err
+			}
+			results = append(results, res)
+		}
+	}
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+	return writeCSV(w, results)
+}
+
+func runAnalyze(format string, patterns []string, output string, max, window int, w io.Writer) error {
+	if output == "" {
+		output = "text"
+	}
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unsupported output format %s", output)
+	}
+	if window <= 0 {
+		return fmt.Errorf("invalid window %d", window)
+	}
+	r, err := openFiles(patterns)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	p, err := traces.NewProvider(format, r)
+	if err != nil {
+		return err
+	}
+	a := traces.Analyze(p, window, max)
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(a)
+	}
+	return a.WriteText(w)
+}
+
+// openFiles opens all trace files matching the given patterns.
+func openFiles(patterns []string) (*traces.FilesReader, error) {
+	var files []string
+	for _, p := range patterns {
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s not found", p)
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no trace files")
+	}
+	return traces.OpenFiles(files...)
+}
+
+// simulate runs the simulation and recovers from panics due to
+// unsupported policies.
+func simulate(p traces.Provider, policy string, size, max int) (res result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	r := traces.Simulate(p, policy, size, max)
+	res = result{
+		Policy:      r.Policy,
+		CacheSize:   r.CacheSize,
+		Requests:    r.Stats.RequestCount(),
+		Hits:        r.Stats.HitCount,
+		HitRate:     r.Stats.HitRate(),
+		ByteHitRate: r.Stats.ByteHitRate(),
+		Evictions:   r.Stats.EvictionCount,
+		Duration:    r.Duration.Seconds(),
+		Throughput:  r.Throughput(),
+	}
+	return res, nil
+}
+
+func writeCSV(w io.Writer, results []result) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Policy", "CacheSize", "Requests", "Hits", "HitRate", "ByteHitRate", "Evictions", "Duration", "Throughput"})
+	for _, r := range results {
+		cw.Write([]string{
+			r.Policy,
+			strconv.Itoa(r.CacheSize),
+			strconv.FormatUint(r.Requests, 10),
+			strconv.FormatUint(r.Hits, 10),
+			strconv.FormatFloat(r.HitRate, 'f', 4, 64),
+			strconv.FormatFloat(r.ByteHitRate, 'f', 4, 64),
+			strconv.FormatUint(r.Evictions, 10),
+			strconv.FormatFloat(r.Duration, 'f', 3, 64),
+			strconv.FormatFloat(r.Throughput, 'f', 0, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// split splits comma-separated values.
+func split(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
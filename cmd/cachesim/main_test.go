@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTrace(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "cachesim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	for i := 0; i < 1000; i++ {
+		binary.Write(&b, binary.LittleEndian, uint32(i%100))
+	}
+	name := filepath.Join(dir, "test.trace")
+	if err = ioutil.WriteFile(name, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestRunCSV(t *testing.T) {
+	name := writeTrace(t)
+	defer os.RemoveAll(filepath.Dir(name))
+
+	var w bytes.Buffer
+	err := run("cache2k", []string{name}, []string{"lru", "tinylfu"}, "50,200", "csv", 0, &w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(w.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("unexpected output: %s", w.String())
+	}
+	if !strings.HasPrefix(lines[4], "tinylfu,200,1000,900,0.9000,") {
+		t.Fatalf("unexpected output: %s", lines[4])
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	name := writeTrace(t)
+	defer os.RemoveAll(filepath.Dir(name))
+
+	var w bytes.Buffer
+	err := run("cache2k", []string{name}, []string{"lru"}, "200", "json", 500, &w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []result
+	if err = json.Unmarshal(w.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Requests != 500 || results[0].Hits != 400 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
+
+func TestRunError(t *testing.T) {
+	name := writeTrace(t)
+	defer os.RemoveAll(filepath.Dir(name))
+
+	var w bytes.Buffer
+	if err := run("unknown", []string{name}, []string{"lru"}, "200", "csv", 0, &w); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := run("cache2k", []string{name}, []string{"unknown"}, "200", "csv", 0, &w); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestRunAnalyze(t *testing.T) {
+	name := writeTrace(t)
+	defer os.RemoveAll(filepath.Dir(name))
+
+	var w bytes.Buffer
+	if err := runAnalyze("cache2k", []string{name}, "", 0, 100, &w); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(w.String(), "Unique keys:     100\n") {
+		t.Fatalf("unexpected output: %s", w.String())
+	}
+	w.Reset()
+	if err := runAnalyze("cache2k", []string{name}, "json", 0, 100, &w); err != nil {
+		t.Fatal(err)
+	}
+	var a struct {
+		Requests int `json:"requests"`
+		Scans    int `json:"scans"`
+	}
+	if err := json.Unmarshal(w.Bytes(), &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Requests != 1000 || a.Scans != 10 {
+		t.Fatalf("unexpected output: %s", w.String())
+	}
+	if err := runAnalyze("cache2k", []string{name}, "csv", 0, 100, &w); err == nil {
+		t.Fatal("expected error")
+	}
+}
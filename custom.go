@@ -0,0 +1,140 @@
+package cache
+
+import "time"
+
+// Entry is an opaque handle of a cache entry given to a custom Policy.
+type Entry entry
+
+// Key returns the entry key.
+func (e *Entry) Key() Key {
+	return e.key
+}
+
+// Value returns the current entry value.
+func (e *Entry) Value() Value {
+	return (*entry)(e).getValue()
+}
+
+// Hash returns hash of the entry key.
+func (e *Entry) Hash() uint64 {
+	return e.hash
+}
+
+// LoadCost returns the time spent loading the entry value, or zero if
+// the value was not loaded by the cache loader.
+func (e *Entry) LoadCost() time.Duration {
+	return time.Duration((*entry)(e).getLoadCost())
+}
+
+// Weight returns the entry weight computed by the cache Weigher, or zero if
+// the weigher is not set.
+func (e *Entry) Weight() int {
+	return int((*entry)(e).getWeight())
+}
+
+// Data returns the data attached to the entry by SetData.
+func (e *Entry) Data() interface{} {
+	return e.data
+}
+
+// SetData attaches policy data, such as a list element, to the entry.
+func (e *Entry) SetData(v interface{}) {
+	e.data = v
+}
+
+// Policy is a user-defined cache replacement policy.
+//
+// All methods of a Policy are called from a single goroutine which processes
+// cache events, so they do not need to be synchronized and must not block.
+// Methods must not call the cache either as that can cause a deadlock.
+type Policy interface {
+	// Init is called once before the cache is used. A non-positive
+	// maximumSize means the cache is unbounded.
+	Init(maximumSize int)
+	// Add adds a new entry to the policy. It returns an entry, which can be
+	// the new one, to be evicted when the cache is full, or nil otherwise.
+	// The returned entry must also be removed from the policy.
+	Add(*Entry) *Entry
+	// Update is called when an existing entry is updated with a new value.
+	Update(*Entry)
+	// Access is called when an entry is read. Accesses are sampled so not
+	// all reads are recorded.
+	Access(*Entry)
+	// Remove removes an entry which was invalidated or expired.
+	Remove(*Entry)
+	// Iterate calls fn for each entry, preferably from the least to the
+	// most recently accessed, until fn returns false. fn may remove the
+	// entry being visited.
+	Iterate(fn func(*Entry) bool)
+}
+
+const (
+	customUnregistered uint8 = iota
+	customRegistered
+)
+
+// customPolicy adapts Policy to policy.
+type customPolicy struct {
+	cache  *cache
+	policy Policy
+}
+
+func (p *customPolicy) init(c *cache, cap int) {
+	p.cache = c
+	p.policy.Init(cap)
+}
+
+func (p *customPolicy) write(en *entry) *entry {
+	if en.listID == customRegistered {
+		p.policy.Update((*Entry)(en))
+		return nil
+	}
+	cen := p.cache.getOrSet(en)
+	if cen != nil {
+		// Entry has already been added, update its value instead.
+		cen.setValue(en.getValue())
+		cen.setWriteTime(en.getWriteTime())
+		if cen.listID == customRegistered {
+			p.policy.Update((*Entry)(cen))
+			return nil
+		}
+		// Entry is loaded to the cache but not yet registered.
+		en = cen
+	}
+	en.listID = customRegistered
+	ren := p.policy.Add((*Entry)(en))
+	if ren == nil {
+		return nil
+	}
+	return p.evict((*entry)(ren))
+}
+
+// evict deletes the entry evicted by the policy from the cache.
+func (p *customPolicy) evict(en *entry) *entry {
+	if en.listID != customRegistered {
+		return nil
+	}
+	en.listID = customUnregistered
+	p.cache.delete(en)
+	return en
+}
+
+func (p *customPolicy) access(en *entry) {
+	if en.listID == customRegistered {
+		p.policy.Access((*Entry)(en))
+	}
+}
+
+func (p *customPolicy) remove(en *entry) *entry {
+	if en.listID != customRegistered {
+		return nil
+	}
+	p.policy.Remove((*Entry)(en))
+	return p.evict(en)
+}
+
+func (p *customPolicy) iterate(fn func(*entry) bool) {
+	p.policy.Iterate(func(en *Entry) bool {
+		return fn((*entry)(en))
+	})
+}
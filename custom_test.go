@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"container/list"
+	"testing"
+	"time"
+)
+
+// priorityPolicy evicts the entry with the lowest priority value.
+type priorityPolicy struct {
+	cap int
+	ls  list.List
+}
+
+func (p *priorityPolicy) Init(maximumSize int) {
+	p.cap = maximumSize
+	p.ls.Init()
+}
+
+func (p *priorityPolicy) Add(en *Entry) *Entry {
+	en.SetData(p.ls.PushBack(en))
+	if p.cap <= 0 || p.ls.Len() <= p.cap {
+		return nil
+	}
+	var victim *Entry
+	for el := p.ls.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*Entry)
+		if victim == nil || e.Value().(int) < victim.Value().(int) {
+			victim = e
+		}
+	}
+	p.Remove(victim)
+	return victim
+}
+
+func (p *priorityPolicy) Update(en *Entry) {
+}
+
+func (p *priorityPolicy) Access(en *Entry) {
+	p.ls.MoveToBack(en.Data().(*list.Element))
+}
+
+func (p *priorityPolicy) Remove(en *Entry) {
+	p.ls.Remove(en.Data().(*list.Element))
+	en.SetData(nil)
+}
+
+func (p *priorityPolicy) Iterate(fn func(*Entry) bool) {
+	for el := p.ls.Front(); el != nil; {
+		next := el.Next()
+		if !fn(el.Value.(*Entry)) {
+			return
+		}
+		el = next
+	}
+}
+
+func TestCustomPolicy(t *testing.T) {
+	p := &priorityPolicy{}
+	removed := make(chan Key, 10)
+	c := New(WithCustomPolicy(p), WithMaximumSize(2), WithRemovalListener(func(k Key, v Value) {
+		removed <- k
+	}))
+	defer c.Close()
+	assertRemoved := func(k Key) {
+		t.Helper()
+		select {
+		case rk := <-removed:
+			if rk != k {
+				t.Fatalf("unexpected entry removed: %v, want: %v", rk, k)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("entry not removed: %v", k)
+		}
+	}
+
+	c.Put("a", 5)
+	c.Put("b", 1)
+	c.Put("c", 3)
+	assertRemoved("b")
+	// New entry has the lowest priority.
+	c.Put("d", 0)
+	assertRemoved("d")
+	c.Invalidate("a")
+	assertRemoved("a")
+	if _, ok := c.GetIfPresent("c"); !ok {
+		t.Fatalf("entry not found: %v", "c")
+	}
+	c.Put("c", 4)
+	c.InvalidateAll()
+	assertRemoved("c")
+	if p.ls.Len() != 0 {
+		t.Fatalf("unexpected policy length: %d", p.ls.Len())
+	}
+}
+
+func TestCustomPolicyShards(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	New(WithCustomPolicy(&priorityPolicy{}), WithShards(2))
+}
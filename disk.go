@@ -0,0 +1,687 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// Default maximum total size of live records in a disk cache.
+	defaultDiskMaximumBytes = 1 << 30
+	// Default maximum size of a segment file.
+	defaultDiskSegmentSize = 64 << 20
+	// Average entry size which is used to estimate number of entries
+	// for the frequency sketch.
+	diskAverageEntrySize = 64 << 10
+	// Minimum number of entries for the frequency sketch.
+	diskMinimumEntries = 64
+
+	diskSegmentExt = ".seg"
+	// Record header: crc32 (4), op (1), key length (4), value length (4).
+	diskHeaderSize = 13
+)
+
+const (
+	diskOpPut byte = iota
+	diskOpDelete
+)
+
+var errDiskCorrupted = errors.New("cache: corrupted disk record")
+
+// Serializer encodes and decodes keys and values stored in a disk cache.
+type Serializer interface {
+	Marshal(interface{}) ([]byte, error)
+	Unmarshal([]byte) (interface{}, error)
+}
+
+// gobSerializer is the default Serializer using encoding/gob.
+// Custom types must be registered with gob.Register.
+type gobSerializer struct{}
+
+func (gobSerializer) Marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	err := gob.NewEncoder(&b).Encode(&v)
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (gobSerializer) Unmarshal(b []byte) (interface{}, error) {
+	var v interface{}
+	err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// diskSegment is an append-only file of records.
+type diskSegment struct {
+	id   uint64
+	f    *os.File
+	size int64 // Total size of records in this segment.
+	// live is the size of records which are still referenced. Segments
+	// without live records are deleted without being scanned.
+	live int64
+}
+
+// diskLocation is the location of a record, stored as entry value.
+type diskLocation struct {
+	seg      *diskSegment
+	offset   int64
+	keyLen   uint32
+	valueLen uint32
+}
+
+func (l *diskLocation) size() int64 {
+	return diskHeaderSize + int64(l.keyLen) + int64(l.valueLen)
+}
+
+// diskCache is a file-backed cache which stores serialized values in segment
+// files and keeps an index of all entries in memory.
+// Entries are managed by a segmented LRU bounded by total size of records.
+type diskCache struct {
+	mu sync.Mutex
+
+	// user configurations
+	dir         string
+	maxBytes    int64
+	segmentSize int64
+	policyName  string
+	serializer  Serializer
+
+	// index is the in-memory index of entries which values are *diskLocation.
+	index  cache
+	hasher hasher
+	slru   slruCache
+	// freq is the frequency sketch for admission when policy is tinylfu.
+	freq      tinyLFU
+	admission bool
+
+	// segments is ordered by id, the last one is the active segment.
+	segments []*diskSegment
+	nextID   uint64
+
+	liveBytes      int64
+	diskBytes      int64
+	protectedBytes int64
+	protectedMax   int64
+
+	stats  statsCounter
+	closed bool
+}
+
+// NewDiskCache returns a Cache which stores serialized values in segment files
+// under directory dir. Entries stored in dir by a previous cache are loaded.
+// Errors when writing entries are ignored and these entries are not cached.
+func NewDiskCache(dir string, options ...DiskOption) (Cache, error) {
+	d := &diskCache{
+		dir:         dir,
+		maxBytes:    defaultDiskMaximumBytes,
+		segmentSize: defaultDiskSegmentSize,
+		serializer:  gobSerializer{},
+	}
+	for _, opt := range options {
+		opt(d)
+	}
+	if err := d.init(); err != nil {
+		d.closeSegments()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *diskCache) init() error {
+	switch d.policyName {
+	case "", "slru":
+	case "tinylfu":
+		d.admission = true
+		n := int(d.maxBytes / diskAverageEntrySize)
+		if n < diskMinimumEntries {
+			n = diskMinimumEntries
+		}
+		d.freq.initSketch(n)
+	default:
+		return fmt.Errorf("cache: unsupported disk cache policy %s", d.policyName)
+	}
+	d.index.init(defaultConcurrencyLevel)
+	d.hasher.init()
+	// The segmented LRU is unbounded as entries are evicted by their size.
+	d.slru.init(&d.index, 0)
+	d.protectedMax = int64(float64(d.maxBytes) * protectedRatio)
+
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return err
+	}
+	names, err := filepath.Glob(filepath.Join(d.dir, "*"+diskSegmentExt))
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		id, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(name), diskSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		if err = d.load(name, id, i == len(names)-1); err != nil {
+			return err
+		}
+	}
+	if len(d.segments) == 0 {
+		if err = d.roll(); err != nil {
+			return err
+		}
+	}
+	d.evict()
+	return nil
+}
+
+// GetIfPresent reads value associated with k from disk. The record is read
+// and decoded without holding the lock.
+func (d *diskCache) GetIfPresent(k Key) (Value, bool) {
+	h := d.hasher.sum(k)
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		d.stats.RecordMisses(1)
+		return nil, false
+	}
+	d.freq.increase(h)
+	for {
+		en := d.index.get(k, h)
+		if en == nil {
+			d.mu.Unlock()
+			d.stats.RecordMisses(1)
+			return nil, false
+		}
+		loc := en.getValue().(*diskLocation)
+		d.mu.Unlock()
+		v, err := d.read(loc)
+		d.mu.Lock()
+		if d.closed || en.accessList == nil {
+			// Removed while reading.
+			d.mu.Unlock()
+			d.stats.RecordMisses(1)
+			return nil, false
+		}
+		if en.getValue() != loc {
+			// Updated or moved by compaction while reading.
+			continue
+		}
+		if err != nil {
+			d.remove(en)
+			d.mu.Unlock()
+			d.stats.RecordMisses(1)
+			return nil, false
+		}
+		d.access(en)
+		d.mu.Unlock()
+		d.stats.RecordHits(1)
+		return v, true
+	}
+}
+
+// Put writes value to disk. The entry is not added if it is not admitted
+// by the cache policy or its size exceeds the cache capacity.
+func (d *diskCache) Put(k Key, v Value) {
+	kb, err := d.serializer.Marshal(k)
+	if err != nil {
+		return
+	}
+	vb, err := d.serializer.Marshal(v)
+	if err != nil {
+		return
+	}
+	h := d.hasher.sum(k)
+	rec := encodeDiskRecord(diskOpPut, kb, vb)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	d.freq.increase(h)
+	en := d.index.get(k, h)
+	if en == nil && !d.admit(h, int64(len(rec))) {
+		return
+	}
+	loc, err := d.append(rec, true)
+	if err != nil {
+		return
+	}
+	if en == nil {
+		en = newEntry(k, loc, h)
+		d.slru.write(en)
+		d.track(en, loc.size())
+	} else {
+		d.release(en)
+		en.setValue(loc)
+		d.track(en, loc.size())
+		d.access(en)
+	}
+	d.evict()
+	d.compact()
+}
+
+// Invalidate removes the entry associated with k.
+func (d *diskCache) Invalidate(k Key) {
+	h := d.hasher.sum(k)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	en := d.index.get(k, h)
+	if en != nil {
+		d.remove(en)
+		d.compact()
+	}
+}
+
+// InvalidateAll removes all entries and segment files.
+func (d *diskCache) InvalidateAll() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	d.slru.iterate(func(en *entry) bool {
+		d.slru.remove(en)
+		return true
+	})
+	for _, seg := range d.segments {
+		seg.f.Close()
+		os.Remove(seg.f.Name())
+	}
+	d.segments = nil
+	d.liveBytes = 0
+	d.diskBytes = 0
+	d.protectedBytes = 0
+	// The active segment is created by the next append if this fails.
+	d.roll()
+}
+
+// Stats copies cache stats to t.
+func (d *diskCache) Stats(t *Stats) {
+	d.stats.Snapshot(t)
+}
+
+// Close syncs and closes all segment files.
+func (d *diskCache) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	return d.closeSegments()
+}
+
+func (d *diskCache) closeSegments() error {
+	var err error
+	for _, seg := range d.segments {
+		if e := seg.f.Sync(); e != nil && err == nil {
+			err = e
+		}
+		if e := seg.f.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// access marks the entry accessed in the segmented LRU and demotes entries
+// in the protected segment when it exceeds its size.
+func (d *diskCache) access(en *entry) {
+	id := en.listID
+	d.slru.access(en)
+	if id != protectedSegment && en.listID == protectedSegment {
+		d.protectedBytes += en.getValue().(*diskLocation).size()
+	}
+	for d.protectedBytes > d.protectedMax {
+		den := d.slru.demote()
+		if den == nil {
+			break
+		}
+		d.protectedBytes -= den.getValue().(*diskLocation).size()
+	}
+}
+
+// track adds delta to total size of live records.
+func (d *diskCache) track(en *entry, delta int64) {
+	d.liveBytes += delta
+	if en.listID == protectedSegment {
+		d.protectedBytes += delta
+	}
+}
+
+// release marks the current record of the entry as dead.
+func (d *diskCache) release(en *entry) {
+	loc := en.getValue().(*diskLocation)
+	loc.seg.live -= loc.size()
+	d.track(en, -loc.size())
+}
+
+// remove removes the entry and writes a delete record for its key.
+func (d *diskCache) remove(en *entry) {
+	d.discard(en)
+	kb, err := d.serializer.Marshal(en.key)
+	if err == nil {
+		d.append(encodeDiskRecord(diskOpDelete, kb, nil), false)
+	}
+}
+
+// discard removes the entry from the index.
+func (d *diskCache) discard(en *entry) {
+	d.release(en)
+	d.slru.remove(en)
+}
+
+// victim returns the next entry to be evicted.
+func (d *diskCache) victim() *entry {
+	if el := d.slru.probationLs.Back(); el != nil {
+		return getEntry(el)
+	}
+	if el := d.slru.protectedLs.Back(); el != nil {
+		return getEntry(el)
+	}
+	return nil
+}
+
+// admit returns whether a new entry with given hash and size should be added.
+func (d *diskCache) admit(h uint64, size int64) bool {
+	if size > d.maxBytes {
+		return false
+	}
+	if !d.admission || d.liveBytes+size <= d.maxBytes {
+		return true
+	}
+	victim := d.victim()
+	if victim == nil {
+		return true
+	}
+	return d.freq.estimate(h) > d.freq.estimate(victim.hash)
+}
+
+// evict removes entries until total size of live records is within the limit.
+func (d *diskCache) evict() {
+	for d.liveBytes > d.maxBytes {
+		en := d.victim()
+		if en == nil {
+			return
+		}
+		d.remove(en)
+		d.stats.RecordEviction()
+	}
+}
+
+// compact rewrites the oldest segments while dead records take more space
+// than live records.
+func (d *diskCache) compact() {
+	for {
+		dead := d.diskBytes - d.liveBytes
+		if dead < d.segmentSize || dead <= d.liveBytes {
+			return
+		}
+		if err := d.compactOldest(); err != nil {
+			return
+		}
+	}
+}
+
+// compactOldest copies live records in the oldest segment to the active
+// segment and deletes it. Delete records in the oldest segment are dropped
+// as there are no older records of their keys.
+func (d *diskCache) compactOldest() error {
+	seg := d.segments[0]
+	if len(d.segments) == 1 {
+		if err := d.roll(); err != nil {
+			return err
+		}
+	}
+	if seg.live > 0 {
+		if err := d.moveLive(seg); err != nil {
+			return err
+		}
+	}
+	d.segments = d.segments[1:]
+	d.diskBytes -= seg.size
+	seg.f.Close()
+	return os.Remove(seg.f.Name())
+}
+
+// moveLive copies live records in the segment to the active segment.
+func (d *diskCache) moveLive(seg *diskSegment) error {
+	_, err := d.scan(seg, func(offset int64, op byte, kb []byte, size int64) error {
+		if op != diskOpPut {
+			return nil
+		}
+		k, err := d.serializer.Unmarshal(kb)
+		if err != nil {
+			return nil
+		}
+		en := d.index.get(k, d.hasher.sum(k))
+		if en == nil {
+			return nil
+		}
+		loc := en.getValue().(*diskLocation)
+		if loc.seg != seg || loc.offset != offset {
+			return nil
+		}
+		rec := make([]byte, size)
+		if _, err = seg.f.ReadAt(rec, offset); err != nil {
+			return err
+		}
+		nloc, err := d.append(rec, true)
+		if err != nil {
+			return err
+		}
+		seg.live -= size
+		en.setValue(nloc)
+		return nil
+	})
+	return err
+}
+
+// roll creates a new active segment.
+func (d *diskCache) roll() error {
+	name := filepath.Join(d.dir, fmt.Sprintf("%020d%s", d.nextID, diskSegmentExt))
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	d.segments = append(d.segments, &diskSegment{id: d.nextID, f: f})
+	d.nextID++
+	return nil
+}
+
+// append writes the record to the active segment, creating a new one if
+// there is none or the active segment is full.
+func (d *diskCache) append(rec []byte, live bool) (*diskLocation, error) {
+	var seg *diskSegment
+	if n := len(d.segments); n > 0 {
+		seg = d.segments[n-1]
+	}
+	if seg == nil || seg.size > 0 && seg.size+int64(len(rec)) > d.segmentSize {
+		if err := d.roll(); err != nil {
+			return nil, err
+		}
+		seg = d.segments[len(d.segments)-1]
+	}
+	if _, err := seg.f.WriteAt(rec, seg.size); err != nil {
+		return nil, err
+	}
+	loc := &diskLocation{
+		seg:      seg,
+		offset:   seg.size,
+		keyLen:   binary.LittleEndian.Uint32(rec[5:]),
+		valueLen: binary.LittleEndian.Uint32(rec[9:]),
+	}
+	seg.size += int64(len(rec))
+	d.diskBytes += int64(len(rec))
+	if live {
+		seg.live += int64(len(rec))
+	}
+	return loc, nil
+}
+
+// read reads and decodes value at the given location.
+func (d *diskCache) read(loc *diskLocation) (Value, error) {
+	rec := make([]byte, loc.size())
+	if _, err := loc.seg.f.ReadAt(rec, loc.offset); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(rec[4:]) != binary.LittleEndian.Uint32(rec) {
+		return nil, errDiskCorrupted
+	}
+	return d.serializer.Unmarshal(rec[diskHeaderSize+loc.keyLen:])
+}
+
+// load opens an existing segment file and adds its records to the index.
+// Incomplete records at the end of the last segment are truncated.
+func (d *diskCache) load(name string, id uint64, last bool) error {
+	f, err := os.OpenFile(name, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	seg := &diskSegment{id: id, f: f}
+	d.segments = append(d.segments, seg)
+	if id >= d.nextID {
+		d.nextID = id + 1
+	}
+	end, err := d.scan(seg, func(offset int64, op byte, kb []byte, size int64) error {
+		k, err := d.serializer.Unmarshal(kb)
+		if err != nil {
+			return nil
+		}
+		h := d.hasher.sum(k)
+		en := d.index.get(k, h)
+		if op == diskOpDelete {
+			if en != nil {
+				d.discard(en)
+			}
+			return nil
+		}
+		loc := &diskLocation{
+			seg:      seg,
+			offset:   offset,
+			keyLen:   uint32(len(kb)),
+			valueLen: uint32(size - diskHeaderSize - int64(len(kb))),
+		}
+		seg.live += size
+		if en == nil {
+			en = newEntry(k, loc, h)
+			d.slru.write(en)
+		} else {
+			d.release(en)
+			en.setValue(loc)
+		}
+		d.track(en, size)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	seg.size = fi.Size()
+	if last && end < seg.size {
+		if err = f.Truncate(end); err != nil {
+			return err
+		}
+		seg.size = end
+	}
+	d.diskBytes += seg.size
+	return nil
+}
+
+// scan calls fn for each record in the segment and returns the end offset
+// of the last complete record.
+func (d *diskCache) scan(seg *diskSegment, fn func(offset int64, op byte, key []byte, size int64) error) (int64, error) {
+	fi, err := seg.f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	end := fi.Size()
+	hdr := make([]byte, diskHeaderSize)
+	var offset int64
+	for offset+diskHeaderSize <= end {
+		if _, err = seg.f.ReadAt(hdr, offset); err != nil {
+			return offset, err
+		}
+		op := hdr[4]
+		keyLen := int64(binary.LittleEndian.Uint32(hdr[5:]))
+		size := diskHeaderSize + keyLen + int64(binary.LittleEndian.Uint32(hdr[9:]))
+		if op > diskOpDelete || offset+size > end {
+			break
+		}
+		key := make([]byte, keyLen)
+		if _, err = seg.f.ReadAt(key, offset+diskHeaderSize); err != nil {
+			return offset, err
+		}
+		if err = fn(offset, op, key, size); err != nil {
+			return offset, err
+		}
+		offset += size
+	}
+	return offset, nil
+}
+
+func encodeDiskRecord(op byte, key, value []byte) []byte {
+	b := make([]byte, diskHeaderSize+len(key)+len(value))
+	b[4] = op
+	binary.LittleEndian.PutUint32(b[5:], uint32(len(key)))
+	binary.LittleEndian.PutUint32(b[9:], uint32(len(value)))
+	copy(b[diskHeaderSize:], key)
+	copy(b[diskHeaderSize+len(key):], value)
+	binary.LittleEndian.PutUint32(b, crc32.ChecksumIEEE(b[4:]))
+	return b
+}
+
+// DiskOption add options for disk cache.
+type DiskOption func(d *diskCache)
+
+// WithDiskMaximumBytes returns a DiskOption which sets maximum total size
+// of entries stored in the disk cache.
+func WithDiskMaximumBytes(n int64) DiskOption {
+	return func(d *diskCache) {
+		d.maxBytes = n
+	}
+}
+
+// WithDiskSegmentSize returns a DiskOption which sets maximum size of each
+// segment file.
+func WithDiskSegmentSize(n int64) DiskOption {
+	return func(d *diskCache) {
+		d.segmentSize = n
+	}
+}
+
+// WithDiskPolicy returns a DiskOption which sets cache policy of the disk cache.
+// Supported policies are: slru, tinylfu.
+func WithDiskPolicy(name string) DiskOption {
+	return func(d *diskCache) {
+		d.policyName = name
+	}
+}
+
+// WithDiskSerializer returns a DiskOption which sets serializer for keys and
+// values. By default, keys and values are encoded using encoding/gob.
+func WithDiskSerializer(s Serializer) DiskOption {
+	return func(d *diskCache) {
+		d.serializer = s
+	}
+}
@@ -0,0 +1,250 @@
+package cache
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func newTestDiskCache(t *testing.T, dir string, options ...DiskOption) *diskCache {
+	c, err := NewDiskCache(dir, options...)
+	if err != nil {
+		t.Helper()
+		t.Fatal(err)
+	}
+	return c.(*diskCache)
+}
+
+func TestDiskCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := newTestDiskCache(t, dir)
+	c.Put("a", []byte("1"))
+	c.Put("b", []byte("2"))
+	c.Put(3, "3")
+	c.Put("b", []byte("22"))
+	c.Invalidate("a")
+
+	v, ok := c.GetIfPresent("b")
+	if !ok || !bytes.Equal(v.([]byte), []byte("22")) {
+		t.Fatalf("unexpected value: %v (%v)", v, ok)
+	}
+	_, ok = c.GetIfPresent("a")
+	if ok {
+		t.Fatalf("unexpected value for %v", "a")
+	}
+	if err = c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen
+	c = newTestDiskCache(t, dir)
+	defer c.Close()
+	v, ok = c.GetIfPresent("b")
+	if !ok || !bytes.Equal(v.([]byte), []byte("22")) {
+		t.Fatalf("unexpected value: %v (%v)", v, ok)
+	}
+	v, ok = c.GetIfPresent(3)
+	if !ok || v.(string) != "3" {
+		t.Fatalf("unexpected value: %v (%v)", v, ok)
+	}
+	_, ok = c.GetIfPresent("a")
+	if ok {
+		t.Fatalf("unexpected value for %v", "a")
+	}
+	var st Stats
+	c.Stats(&st)
+	if st.HitCount != 2 || st.MissCount != 1 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+
+	c.InvalidateAll()
+	_, ok = c.GetIfPresent("b")
+	if ok {
+		t.Fatalf("unexpected value for %v", "b")
+	}
+}
+
+func TestDiskCacheMaximumBytes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const max = 4096
+	value := make([]byte, 100)
+	for _, p := range []string{"slru", "tinylfu"} {
+		c := newTestDiskCache(t, filepath.Join(dir, p), WithDiskMaximumBytes(max),
+			WithDiskSegmentSize(1024), WithDiskPolicy(p))
+		for i := 0; i < 100; i++ {
+			c.Put(i, value)
+			if c.liveBytes > max {
+				t.Fatalf("%s: unexpected live bytes: %d, want: <= %d", p, c.liveBytes, max)
+			}
+			if c.diskBytes > 4*max {
+				t.Fatalf("%s: unexpected disk bytes: %d", p, c.diskBytes)
+			}
+		}
+		// Frequently requested entry should be admitted.
+		for i := 0; i < 5; i++ {
+			c.GetIfPresent("hot")
+		}
+		c.Put("hot", value)
+		_, ok := c.GetIfPresent("hot")
+		if !ok {
+			t.Fatalf("%s: expect entry admitted", p)
+		}
+		var st Stats
+		c.Stats(&st)
+		if st.EvictionCount == 0 {
+			t.Fatalf("%s: unexpected stats: %+v", p, st)
+		}
+		c.Close()
+	}
+}
+
+func TestDiskCacheCompaction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := newTestDiskCache(t, dir, WithDiskSegmentSize(1024))
+	for i := 0; i < 1000; i++ {
+		c.Put(i%10, i)
+	}
+	if c.diskBytes > 2*c.liveBytes+1024 {
+		t.Fatalf("unexpected disk bytes: %d, live bytes: %d", c.diskBytes, c.liveBytes)
+	}
+	c.Close()
+
+	c = newTestDiskCache(t, dir, WithDiskSegmentSize(1024))
+	defer c.Close()
+	for i := 0; i < 10; i++ {
+		v, ok := c.GetIfPresent(i)
+		if !ok || v.(int) != 990+i {
+			t.Fatalf("unexpected value: %v (%v), want: %v", v, ok, 990+i)
+		}
+	}
+}
+
+func TestDiskCacheLiveBytes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := newTestDiskCache(t, dir, WithDiskSegmentSize(1024), WithDiskMaximumBytes(2048))
+	defer c.Close()
+	for i := 0; i < 1000; i++ {
+		c.Put(i%50, i)
+		if i%7 == 0 {
+			c.Invalidate(i % 30)
+		}
+	}
+	var live int64
+	for _, seg := range c.segments {
+		live += seg.live
+	}
+	if live != c.liveBytes {
+		t.Fatalf("unexpected live bytes of segments: %d, want: %d", live, c.liveBytes)
+	}
+}
+
+func TestDiskCacheInvalidateAllError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := newTestDiskCache(t, filepath.Join(dir, "c"))
+	defer c.Close()
+	c.Put(1, 1)
+	// New segment cannot be created without the directory.
+	if err = os.RemoveAll(filepath.Join(dir, "c")); err != nil {
+		t.Fatal(err)
+	}
+	c.InvalidateAll()
+	c.Put(2, 2)
+	if _, ok := c.GetIfPresent(2); ok {
+		t.Fatalf("unexpected value for %v", 2)
+	}
+	if err = os.Mkdir(filepath.Join(dir, "c"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	c.Put(2, 2)
+	if v, ok := c.GetIfPresent(2); !ok || v.(int) != 2 {
+		t.Fatalf("unexpected value: %v (%v)", v, ok)
+	}
+}
+
+func TestDiskCacheConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := newTestDiskCache(t, dir, WithDiskSegmentSize(1024))
+	defer c.Close()
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				if g%2 == 0 {
+					c.Put(i%10, i%10)
+				} else if v, ok := c.GetIfPresent(i % 10); ok && v.(int) != i%10 {
+					t.Errorf("unexpected value: %v, want: %v", v, i%10)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestDiskCacheTruncated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := newTestDiskCache(t, dir)
+	c.Put(1, 1)
+	c.Put(2, 2)
+	name := c.segments[0].f.Name()
+	size := c.segments[0].size
+	c.Close()
+	if err = os.Truncate(name, size-1); err != nil {
+		t.Fatal(err)
+	}
+
+	c = newTestDiskCache(t, dir)
+	defer c.Close()
+	v, ok := c.GetIfPresent(1)
+	if !ok || v.(int) != 1 {
+		t.Fatalf("unexpected value: %v (%v)", v, ok)
+	}
+	_, ok = c.GetIfPresent(2)
+	if ok {
+		t.Fatalf("unexpected value for %v", 2)
+	}
+	c.Put(3, 3)
+	v, ok = c.GetIfPresent(3)
+	if !ok || v.(int) != 3 {
+		t.Fatalf("unexpected value: %v (%v)", v, ok)
+	}
+}
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"sync"
+)
+
+const (
+	// Number of sampled keys shadowed per cache size.
+	shadowSampleSize = 1024
+	// Modulus of key hashes used in shadow sampling.
+	shadowModulus = 1 << 24
+)
+
+// defaultShadowMultipliers are the sizes, relative to the cache maximum
+// size, estimated by default.
+var defaultShadowMultipliers = []float64{0.5, 1, 2, 4}
+
+// HitRateEstimate is the estimated hit rate of an LRU cache of a given size.
+type HitRateEstimate struct {
+	Multiplier float64
+	Size       int
+	Requests   uint64
+	Hits       uint64
+}
+
+// HitRate returns the ratio of sampled requests which would be hits.
+func (e *HitRateEstimate) HitRate() float64 {
+	if e.Requests == 0 {
+		return 0
+	}
+	return float64(e.Hits) / float64(e.Requests)
+}
+
+// HitRateEstimator is implemented by caches created with
+// WithHitRateEstimation.
+type HitRateEstimator interface {
+	// EstimateHitRates returns estimated hit rates at multiples of the cache
+	// maximum size, or nil if the estimation is not enabled.
+	EstimateHitRates() []HitRateEstimate
+}
+
+// shadowCache replays a hash-sampled subset of requests against LRU ghost
+// lists of virtual capacities. Only key hashes are retained.
+// See Waldspurger et al., "Efficient MRC Construction with SHARDS".
+type shadowCache struct {
+	threshold uint64
+	// buffer holds sampled hashes until they are replayed. Hashes are
+	// dropped when it is full, so requests are never blocked.
+	buffer stripedHashBuffer
+
+	mu       sync.Mutex
+	requests uint64
+	ghosts   []shadowGhost
+}
+
+// shadowGhost is a sampled LRU cache of a virtual size.
+type shadowGhost struct {
+	multiplier float64
+	size       int
+	cap        int
+	hits       uint64
+	ls         ghostList
+}
+
+func (s *shadowCache) init(cap int, multipliers []float64) {
+	rate := 1.0
+	if cap > shadowSampleSize {
+		rate = float64(shadowSampleSize) / float64(cap)
+	}
+	s.threshold = uint64(rate * shadowModulus)
+	s.buffer.init()
+	s.ghosts = make([]shadowGhost, len(multipliers))
+	for i, m := range multipliers {
+		g := &s.ghosts[i]
+		g.multiplier = m
+		g.size = int(m * float64(cap))
+		g.cap = int(m * float64(cap) * rate)
+		if g.cap < 1 {
+			g.cap = 1
+		}
+		g.ls.init()
+	}
+}
+
+// offer adds key hash h to the buffer if it is sampled. It returns true if
+// the buffer should be drained.
+func (s *shadowCache) offer(h uint64) bool {
+	if fmix64(h)%shadowModulus >= s.threshold {
+		return false
+	}
+	return s.buffer.offer(h)
+}
+
+// drain replays all buffered requests.
+func (s *shadowCache) drain() {
+	s.mu.Lock()
+	s.buffer.drain(s.replay)
+	s.mu.Unlock()
+}
+
+// replay replays a sampled request of key hash h. s.mu must be held.
+func (s *shadowCache) replay(h uint64) {
+	s.requests++
+	for i := range s.ghosts {
+		g := &s.ghosts[i]
+		if g.ls.contains(h) {
+			g.hits++
+		} else if g.ls.len() >= g.cap {
+			g.ls.removeBack()
+		}
+		g.ls.add(h)
+	}
+}
+
+// estimates returns hit rate estimates of all virtual sizes, including
+// buffered requests.
+func (s *shadowCache) estimates() []HitRateEstimate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buffer.drain(s.replay)
+	e := make([]HitRateEstimate, len(s.ghosts))
+	for i := range s.ghosts {
+		g := &s.ghosts[i]
+		e[i] = HitRateEstimate{
+			Multiplier: g.multiplier,
+			Size:       g.size,
+			Requests:   s.requests,
+			Hits:       g.hits,
+		}
+	}
+	return e
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestShadowCache(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	z := rand.NewZipf(r, 1.01, 1, 10000)
+	keys := make([]uint64, 20000)
+	for i := range keys {
+		keys[i] = z.Uint64()
+	}
+	s := shadowCache{}
+	s.init(100, []float64{1, 2})
+	for _, k := range keys {
+		// Drain every request as buffers of different stripes are not
+		// replayed in the order of requests.
+		s.offer(sum(k))
+		s.drain()
+	}
+	for _, e := range s.estimates() {
+		// Sampling is disabled for small caches, so estimates are exact.
+		sim := NewSimulator(WithMaximumSize(e.Size), WithPolicy("lru"))
+		for _, k := range keys {
+			if _, ok := sim.Get(k); !ok {
+				sim.Put(k, k)
+			}
+		}
+		var st Stats
+		sim.Stats(&st)
+		if e.Requests != st.RequestCount() || e.Hits != st.HitCount {
+			t.Fatalf("unexpected estimate: %+v, want: %+v", e, st)
+		}
+	}
+}
+
+func TestShadowCacheBuffer(t *testing.T) {
+	s := shadowCache{}
+	s.init(100, []float64{1})
+	for i := uint64(0); i < 10; i++ {
+		s.offer(i%5 + 1)
+	}
+	// Buffered requests are replayed before estimating.
+	e := s.estimates()
+	if e[0].Requests != 10 || e[0].Hits != 5 {
+		t.Fatalf("unexpected estimate: %+v", e[0])
+	}
+}
+
+func TestShadowCacheSampling(t *testing.T) {
+	s := shadowCache{}
+	s.init(100*shadowSampleSize, []float64{0.5})
+	for i := uint64(1); i <= 100000; i++ {
+		if s.offer(i) {
+			s.drain()
+		}
+	}
+	e := s.estimates()
+	if e[0].Size != 50*shadowSampleSize || e[0].Requests == 0 || e[0].Requests > 5000 {
+		t.Fatalf("unexpected estimate: %+v", e[0])
+	}
+}
+
+func TestHitRateEstimation(t *testing.T) {
+	c := New(WithMaximumSize(100), WithHitRateEstimation())
+	defer c.Close()
+	for i := 0; i < 1000; i++ {
+		if _, ok := c.GetIfPresent(i % 150); !ok {
+			c.Put(i%150, i)
+		}
+	}
+	e := c.(HitRateEstimator).EstimateHitRates()
+	if len(e) != len(defaultShadowMultipliers) {
+		t.Fatalf("unexpected estimates: %+v", e)
+	}
+	// Requests are dropped when the buffer is full.
+	sizes := []int{50, 100, 200, 400}
+	for i := range e {
+		if e[i].Size != sizes[i] || e[i].Requests == 0 || e[i].Requests > 1000 {
+			t.Fatalf("unexpected estimate: %+v", e[i])
+		}
+	}
+	// Only first requests of keys miss when all keys fit.
+	if e[0].Hits > e[1].Hits || e[1].Hits > e[2].Hits || e[2].Hits < e[2].Requests-150 {
+		t.Fatalf("unexpected estimates: %+v", e)
+	}
+
+	c = New(WithMaximumSize(100))
+	defer c.Close()
+	if e = c.(HitRateEstimator).EstimateHitRates(); e != nil {
+		t.Fatalf("unexpected estimates: %+v", e)
+	}
+}
+
+func TestShardedHitRateEstimation(t *testing.T) {
+	c := New(WithMaximumSize(100), WithShards(4), WithHitRateEstimation(1, 2))
+	defer c.Close()
+	for i := 0; i < 1000; i++ {
+		c.GetIfPresent(i % 50)
+	}
+	e := c.(HitRateEstimator).EstimateHitRates()
+	if len(e) != 2 || e[0].Size != 100 || e[1].Size != 200 ||
+		e[0].Requests == 0 || e[0].Requests > 1000 || e[0].Hits < e[0].Requests-50 {
+		t.Fatalf("unexpected estimates: %+v", e)
+	}
+}
@@ -0,0 +1,252 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// sieveCache is a SIEVE cache. Entries are kept in insertion order and
+// accessing an entry only marks it visited. The hand moves from the oldest
+// entry to the newest, clearing visited entries and evicting the first
+// unvisited one.
+// See https://www.usenix.org/conference/nsdi24/presentation/zhang-yazhuo
+type sieveCache struct {
+	cache *cache
+	cap   int
+	ls    list.List // Newest entry is at the front.
+	hand  *list.Element
+}
+
+// init initializes the cache list.
+func (l *sieveCache) init(c *cache, cap int) {
+	l.cache = c
+	l.cap = cap
+	l.ls.Init()
+	l.hand = nil
+}
+
+// write adds new entry to the cache and returns evicted entry if necessary.
+func (l *sieveCache) write(en *entry) *entry {
+	// Fast path
+	if en.accessList != nil {
+		// Entry existed, update its status instead.
+		en.freq = 1
+		return nil
+	}
+	cen := l.cache.getOrSet(en)
+	if cen != nil {
+		// Entry has already been added, update its value instead.
+		cen.setValue(en.getValue())
+		cen.setWriteTime(en.getWriteTime())
+		if cen.accessList != nil {
+			cen.freq = 1
+			return nil
+		}
+		// Entry is loaded to the cache but not yet registered.
+		en = cen
+	}
+	var ren *entry
+	if l.cap > 0 && l.ls.Len() >= l.cap {
+		ren = l.evict()
+	}
+	en.freq = 0
+	en.accessList = l.ls.PushFront(en)
+	return ren
+}
+
+// evict moves the hand to the first unvisited entry and removes it.
+func (l *sieveCache) evict() *entry {
+	el := l.hand
+	if el == nil {
+		el = l.ls.Back()
+	}
+	for el != nil {
+		en := getEntry(el)
+		if en.freq == 0 {
+			l.hand = el.Prev()
+			return l.remove(en)
+		}
+		en.freq = 0
+		el = el.Prev()
+		if el == nil {
+			el = l.ls.Back()
+		}
+	}
+	return nil
+}
+
+// access marks the entry visited.
+func (l *sieveCache) access(en *entry) {
+	if en.accessList != nil {
+		en.freq = 1
+	}
+}
+
+// remove removes an entry from the cache and returns the removed entry or nil
+// if it is not found.
+func (l *sieveCache) remove(en *entry) *entry {
+	if en.accessList == nil {
+		return nil
+	}
+	if l.hand == en.accessList {
+		l.hand = l.hand.Prev()
+	}
+	l.cache.delete(en)
+	l.ls.Remove(en.accessList)
+	en.accessList = nil
+	return en
+}
+
+// iterate walks through the list by insertion time.
+func (l *sieveCache) iterate(fn func(en *entry) bool) {
+	iterateListFromBack(&l.ls, fn)
+}
+
+const (
+	s3fifoSmall uint8 = iota
+	s3fifoMain
+)
+
+const (
+	// Ratio of the capacity for the small queue.
+	s3fifoSmallRatio = 0.1
+	// Maximum frequency of an entry.
+	s3fifoMaxFreq = 3
+)
+
+// s3fifoCache is a S3-FIFO cache. New entries are inserted into the small
+// queue, and moved to the main queue on eviction if they have been accessed.
+// Otherwise they are evicted and remembered in the ghost queue so they are
+// inserted into the main queue directly when requested again.
+// See https://dl.acm.org/doi/10.1145/3600006.3613147
+type s3fifoCache struct {
+	cache *cache
+	cap   int
+
+	smallCap int
+	small    list.List
+	main     list.List
+	ghost    ghostList
+}
+
+// init initializes the cache queues.
+func (l *s3fifoCache) init(c *cache, cap int) {
+	l.cache = c
+	l.cap = cap
+	l.smallCap = int(float64(cap) * s3fifoSmallRatio)
+	if cap > 0 && l.smallCap < 1 {
+		l.smallCap = 1
+	}
+	l.small.Init()
+	l.main.Init()
+	l.ghost.init()
+}
+
+// length returns number of entries in the cache.
+func (l *s3fifoCache) length() int {
+	return l.small.Len() + l.main.Len()
+}
+
+// write adds new entry to the cache and returns evicted entry if necessary.
+func (l *s3fifoCache) write(en *entry) *entry {
+	// Fast path
+	if en.accessList != nil {
+		// Entry existed, update its status instead.
+		l.markAccess(en)
+		return nil
+	}
+	cen := l.cache.getOrSet(en)
+	if cen != nil {
+		// Entry has already been added, update its value instead.
+		cen.setValue(en.getValue())
+		cen.setWriteTime(en.getWriteTime())
+		if cen.accessList != nil {
+			l.markAccess(cen)
+			return nil
+		}
+		// Entry is loaded to the cache but not yet registered.
+		en = cen
+	}
+	var ren *entry
+	if l.cap > 0 && l.length() >= l.cap {
+		ren = l.evict()
+	}
+	en.freq = 0
+	if l.ghost.remove(en.hash) {
+		en.listID = s3fifoMain
+		en.accessList = l.main.PushFront(en)
+	} else {
+		en.listID = s3fifoSmall
+		en.accessList = l.small.PushFront(en)
+	}
+	return ren
+}
+
+// evict removes exactly one entry from either the small or main queue.
+func (l *s3fifoCache) evict() *entry {
+	for {
+		if l.small.Len() > 0 && (l.small.Len() >= l.smallCap || l.main.Len() == 0) {
+			en := getEntry(l.small.Back())
+			if en.freq > 0 {
+				// Accessed in the small queue, move it to the main queue.
+				l.small.Remove(en.accessList)
+				en.freq = 0
+				en.listID = s3fifoMain
+				en.accessList = l.main.PushFront(en)
+				continue
+			}
+			l.remove(en)
+			l.ghost.add(en.hash)
+			if l.ghost.len() > l.cap-l.smallCap {
+				l.ghost.removeBack()
+			}
+			return en
+		}
+		el := l.main.Back()
+		if el == nil {
+			return nil
+		}
+		en := getEntry(el)
+		if en.freq > 0 {
+			en.freq--
+			l.main.MoveToFront(el)
+			continue
+		}
+		return l.remove(en)
+	}
+}
+
+// access increases frequency of the entry.
+func (l *s3fifoCache) access(en *entry) {
+	if en.accessList != nil {
+		l.markAccess(en)
+	}
+}
+
+// markAccess increases frequency of the entry.
+func (l *s3fifoCache) markAccess(en *entry) {
+	if en.freq < s3fifoMaxFreq {
+		en.freq++
+	}
+}
+
+// remove removes an entry from the cache and returns the removed entry or nil
+// if it is not found.
+func (l *s3fifoCache) remove(en *entry) *entry {
+	if en.accessList == nil {
+		return nil
+	}
+	l.cache.delete(en)
+	if en.listID == s3fifoMain {
+		l.main.Remove(en.accessList)
+	} else {
+		l.small.Remove(en.accessList)
+	}
+	en.accessList = nil
+	return en
+}
+
+// iterate walks through the queues by insertion time.
+func (l *s3fifoCache) iterate(fn func(en *entry) bool) {
+	iterateListFromBack(&l.main, fn)
+	iterateListFromBack(&l.small, fn)
+}
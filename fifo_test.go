@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"testing"
+)
+
+func assertKeys(t *testing.T, p policy, keys ...int) {
+	var actual []Key
+	p.iterate(func(en *entry) bool {
+		actual = append(actual, en.key)
+		return true
+	})
+	if len(actual) != len(keys) {
+		t.Helper()
+		t.Fatalf("unexpected keys: %v, want: %v", actual, keys)
+	}
+	for i := range keys {
+		if actual[i] != keys[i] {
+			t.Helper()
+			t.Fatalf("unexpected keys: %v, want: %v", actual, keys)
+		}
+	}
+}
+
+func assertRemoved(t *testing.T, en *entry, k int) {
+	if k < 0 {
+		if en != nil {
+			t.Helper()
+			t.Fatalf("unexpected entry removed: %+v", en)
+		}
+		return
+	}
+	if en == nil || en.key != k {
+		t.Helper()
+		t.Fatalf("unexpected entry removed: %+v, want: %v", en, k)
+	}
+}
+
+func TestSIEVE(t *testing.T) {
+	c := cache{}
+	c.init(defaultConcurrencyLevel)
+	l := sieveCache{}
+	l.init(&c, 3)
+	write := func(k int) *entry {
+		return l.write(newEntry(k, k, sum(k)))
+	}
+	for i := 0; i < 3; i++ {
+		assertRemoved(t, write(i), -1)
+	}
+	l.access(c.get(0, sum(0)))
+	// 2 1 0*
+	assertRemoved(t, write(3), 1)
+	// 3 2 0
+	assertKeys(t, &l, 0, 2, 3)
+	if en := c.get(0, sum(0)); en.freq != 0 {
+		t.Fatalf("unexpected visited entry: %+v", en)
+	}
+	assertRemoved(t, write(4), 2)
+	// 4 3 0
+	assertRemoved(t, l.remove(c.get(3, sum(3))), 3)
+	assertRemoved(t, write(5), -1)
+	// 5 4 0
+	assertKeys(t, &l, 0, 4, 5)
+	if cacheSize(&c) != 3 {
+		t.Fatalf("unexpected cache size: %d", cacheSize(&c))
+	}
+	assertRemoved(t, write(6), 4)
+	assertKeys(t, &l, 0, 5, 6)
+}
+
+func TestS3FIFO(t *testing.T) {
+	c := cache{}
+	c.init(defaultConcurrencyLevel)
+	l := s3fifoCache{}
+	l.init(&c, 10)
+	write := func(k int) *entry {
+		return l.write(newEntry(k, k, sum(k)))
+	}
+	get := func(k int) *entry {
+		return c.get(k, sum(k))
+	}
+	for i := 0; i < 10; i++ {
+		assertRemoved(t, write(i), -1)
+	}
+	l.access(get(0))
+	l.access(get(1))
+	// Accessed entries are moved to the main queue.
+	assertRemoved(t, write(10), 2)
+	if l.small.Len() != 8 || l.main.Len() != 2 || l.ghost.len() != 1 {
+		t.Fatalf("unexpected length: small=%d main=%d ghost=%d", l.small.Len(), l.main.Len(), l.ghost.len())
+	}
+	// Entry in the ghost queue is inserted into the main queue.
+	assertRemoved(t, write(2), 3)
+	if en := get(2); en.listID != s3fifoMain {
+		t.Fatalf("unexpected entry: %+v", en)
+	}
+	if l.small.Len() != 7 || l.main.Len() != 3 || l.ghost.len() != 1 {
+		t.Fatalf("unexpected length: small=%d main=%d ghost=%d", l.small.Len(), l.main.Len(), l.ghost.len())
+	}
+	l.iterate(func(en *entry) bool {
+		if en.listID == s3fifoSmall {
+			l.remove(en)
+		}
+		return true
+	})
+	assertKeys(t, &l, 0, 1, 2)
+	l.access(get(0))
+	l.access(get(0))
+	assertRemoved(t, l.evict(), 1)
+	if en := get(0); en.freq != 1 {
+		t.Fatalf("unexpected entry frequency: %+v", en)
+	}
+	assertKeys(t, &l, 2, 0)
+	if cacheSize(&c) != 2 {
+		t.Fatalf("unexpected cache size: %d", cacheSize(&c))
+	}
+}
+
+func TestFIFOScan(t *testing.T) {
+	// Frequently used keys should survive a scan.
+	var keys []int
+	for i := 0; i < 100; i++ {
+		for k := 0; k < 50; k++ {
+			keys = append(keys, k, k)
+		}
+		for k := 0; k < 60; k++ {
+			keys = append(keys, 1000+i*100+k)
+		}
+	}
+	lru := replayPolicy(&lruCache{}, keys)
+	for _, p := range []policy{&sieveCache{}, &s3fifoCache{}} {
+		hits := replayPolicy(p, keys)
+		if hits <= lru {
+			t.Fatalf("unexpected hits: %T=%d lru=%d", p, hits, lru)
+		}
+	}
+}
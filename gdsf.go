@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// gdsfNode is the priority of an entry in GDSF.
+type gdsfNode struct {
+	en       *entry
+	freq     uint64
+	priority float64
+	index    int // Index in the heap
+}
+
+// gdsfHeap is a min heap of entry priorities.
+type gdsfHeap []*gdsfNode
+
+func (h gdsfHeap) Len() int {
+	return len(h)
+}
+
+func (h gdsfHeap) Less(i, j int) bool {
+	return h[i].priority < h[j].priority
+}
+
+func (h gdsfHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *gdsfHeap) Push(x interface{}) {
+	n := x.(*gdsfNode)
+	n.index = len(*h)
+	*h = append(*h, n)
+}
+
+func (h *gdsfHeap) Pop() interface{} {
+	old := *h
+	i := len(old) - 1
+	n := old[i]
+	old[i] = nil
+	*h = old[:i]
+	return n
+}
+
+// gdsfCache is a GreedyDual-Size-Frequency cache. Priority of an entry is
+// L + frequency * cost / weight, where cost is the time spent loading the entry and L is the priority of
+// the last evicted entry, which ages entries not accessed recently.
+// Entries with lowest priority are evicted first.
+// See https://www.hpl.hp.com/techreports/98/HPL-98-173.pdf
+type gdsfCache struct {
+	cache *cache
+	cap   int
+	// inflation is the priority of the last evicted entry.
+	inflation float64
+
+	pq gdsfHeap
+	// ls is ordered by access time for iterating.
+	ls list.List
+}
+
+// init initializes the priority queue.
+func (l *gdsfCache) init(c *cache, cap int) {
+	l.cache = c
+	l.cap = cap
+	l.inflation = 0
+	l.pq = nil
+	l.ls.Init()
+}
+
+// write adds new entry to the cache and returns evicted entry if necessary.
+func (l *gdsfCache) write(en *entry) *entry {
+	// Fast path
+	if en.accessList != nil {
+		// Entry existed, its cost may have been changed.
+		l.markAccess(en)
+		return nil
+	}
+	cen := l.cache.getOrSet(en)
+	if cen != nil {
+		// Entry has already been added, update its value instead.
+		cen.setValue(en.getValue())
+		cen.setWriteTime(en.getWriteTime())
+		cen.setLoadCost(en.getLoadCost())
+		cen.setWeight(en.getWeight())
+		if cen.accessList != nil {
+			l.markAccess(cen)
+			return nil
+		}
+		// Entry is loaded to the cache but not yet registered.
+		en = cen
+	}
+	var ren *entry
+	if l.cap > 0 && l.pq.Len() >= l.cap {
+		n := l.pq[0]
+		l.inflation = n.priority
+		ren = l.remove(n.en)
+	}
+	n := &gdsfNode{en: en, freq: 1}
+	n.priority = l.priority(n)
+	en.data = n
+	heap.Push(&l.pq, n)
+	en.accessList = l.ls.PushFront(en)
+	return ren
+}
+
+// priority returns the current priority of node n.
+// Entries which were not loaded have the minimum cost.
+func (l *gdsfCache) priority(n *gdsfNode) float64 {
+	cost := float64(n.en.getLoadCost())
+	if cost < 1 {
+		cost = 1
+	}
+	weight := float64(n.en.getWeight())
+	if weight < 1 {
+		weight = 1
+	}
+	return l.inflation + float64(n.freq)*cost/weight
+}
+
+// access updates cache entry for a get.
+func (l *gdsfCache) access(en *entry) {
+	if en.accessList != nil {
+		l.markAccess(en)
+	}
+}
+
+// markAccess increases frequency and priority of the entry.
+// en.accessList must not be null.
+func (l *gdsfCache) markAccess(en *entry) {
+	n := en.data.(*gdsfNode)
+	n.freq++
+	n.priority = l.priority(n)
+	heap.Fix(&l.pq, n.index)
+	l.ls.MoveToFront(en.accessList)
+}
+
+// remove removes an entry from the cache and returns the removed entry or nil
+// if it is not found.
+func (l *gdsfCache) remove(en *entry) *entry {
+	if en.accessList == nil {
+		return nil
+	}
+	n := en.data.(*gdsfNode)
+	heap.Remove(&l.pq, n.index)
+	en.data = nil
+	l.cache.delete(en)
+	l.ls.Remove(en.accessList)
+	en.accessList = nil
+	return en
+}
+
+// iterate walks through all entries by access time.
+func (l *gdsfCache) iterate(fn func(en *entry) bool) {
+	iterateListFromBack(&l.ls, fn)
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGDSF(t *testing.T) {
+	c := cache{}
+	c.init(defaultConcurrencyLevel)
+	l := gdsfCache{}
+	l.init(&c, 2)
+	write := func(k string, cost int64, weight int32) *entry {
+		en := newEntry(k, k, sum(k))
+		en.setLoadCost(cost)
+		en.setWeight(weight)
+		return l.write(en)
+	}
+	assertRemoved := func(en *entry, k string) {
+		t.Helper()
+		if k == "" {
+			if en != nil {
+				t.Fatalf("unexpected entry removed: %+v", en)
+			}
+			return
+		}
+		if en == nil || en.key != k {
+			t.Fatalf("unexpected entry removed: %+v, want: %v", en, k)
+		}
+	}
+	assertRemoved(write("a", 100, 0), "")
+	assertRemoved(write("b", 1, 0), "")
+	assertRemoved(write("c", 50, 0), "b")
+	if l.inflation != 1 {
+		t.Fatalf("unexpected inflation: %v", l.inflation)
+	}
+	// c: 1+50
+	assertRemoved(write("d", 10, 0), "c")
+	// d: 51+10
+	l.access(c.get("d", sum("d")))
+	// d: 51+2*10
+	assertRemoved(write("e", 1, 0), "d")
+	// e: 71+1
+	assertRemoved(write("f", 200, 10), "e")
+	// f: 72+200/10
+	if n := c.get("f", sum("f")).data.(*gdsfNode); n.priority != 92 {
+		t.Fatalf("unexpected priority: %v", n.priority)
+	}
+	assertRemoved(l.remove(c.get("a", sum("a"))), "a")
+	if cacheSize(&c) != 1 || l.pq.Len() != 1 || l.ls.Len() != 1 {
+		t.Fatalf("unexpected length: cache=%d heap=%d list=%d", cacheSize(&c), l.pq.Len(), l.ls.Len())
+	}
+}
+
+func TestGDSFLoadCost(t *testing.T) {
+	mockTime := newMockTime()
+	currentTime = mockTime.now
+	defer func() {
+		currentTime = time.Now
+	}()
+	loader := func(k Key) (Value, error) {
+		mockTime.add(time.Duration(k.(int)) * time.Millisecond)
+		return k, nil
+	}
+	removed := make(chan Key, 10)
+	c := NewLoadingCache(loader, WithPolicy("gdsf"), WithMaximumSize(2),
+		WithRemovalListener(func(k Key, v Value) {
+			removed <- k
+		}))
+	defer c.Close()
+
+	for _, k := range []int{100, 1, 100} {
+		if _, err := c.Get(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var st Stats
+	c.Stats(&st)
+	if st.HitCount != 1 || st.LoadTimeSaved != 100*time.Millisecond {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+	// The least expensive entry is evicted.
+	if _, err := c.Get(50); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case k := <-removed:
+		if k != 1 {
+			t.Fatalf("unexpected entry removed: %v", k)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("entry not removed")
+	}
+	if _, ok := c.GetIfPresent(100); !ok {
+		t.Fatalf("entry not found: %v", 100)
+	}
+	c.Stats(&st)
+	if st.LoadTimeSaved != 200*time.Millisecond {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
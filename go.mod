@@ -1,3 +1,3 @@
 module github.com/goburrow/cache
 
-go 1.13
+go 1.14
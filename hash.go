@@ -1,8 +1,12 @@
 package cache
 
 import (
+	"encoding/binary"
+	"hash/maphash"
 	"math"
+	"math/bits"
 	"reflect"
+	"sync"
 )
 
 // Hash is an interface implemented by cache keys to
@@ -11,7 +15,139 @@ type Hash interface {
 	Sum64() uint64
 }
 
+// hasher calculates hash values of cache keys. Zero value hasher is
+// deterministic, otherwise hash values are seeded randomly so that they
+// cannot be predicted to craft collisions.
+type hasher struct {
+	seeded bool
+	seed   maphash.Seed
+	// custom overrides the default hash function when it is set.
+	custom func(Key) uint64
+}
+
+// init seeds the hasher randomly.
+func (h *hasher) init() {
+	h.seeded = true
+	h.seed = maphash.MakeSeed()
+}
+
 // sum calculates hash value of the given key.
+func (h *hasher) sum(k interface{}) uint64 {
+	if h.custom != nil {
+		return h.custom(k)
+	}
+	if !h.seeded {
+		return sum(k)
+	}
+	switch v := k.(type) {
+	case Hash:
+		return v.Sum64()
+	case int:
+		return h.mix(uint64(v))
+	case int8:
+		return h.mix(uint64(v))
+	case int16:
+		return h.mix(uint64(v))
+	case int32:
+		return h.mix(uint64(v))
+	case int64:
+		return h.mix(uint64(v))
+	case uint:
+		return h.mix(uint64(v))
+	case uint8:
+		return h.mix(uint64(v))
+	case uint16:
+		return h.mix(uint64(v))
+	case uint32:
+		return h.mix(uint64(v))
+	case uint64:
+		return h.mix(v)
+	case uintptr:
+		return h.mix(uint64(v))
+	case float32:
+		return h.mix(uint64(math.Float32bits(normalizeFloat32(v))))
+	case float64:
+		return h.mix(math.Float64bits(normalizeFloat64(v)))
+	case bool:
+		if v {
+			return h.mix(1)
+		}
+		return h.mix(0)
+	case complex64:
+		return h.complex(complex128(v))
+	case complex128:
+		return h.complex(v)
+	case string:
+		return h.str(v)
+	}
+	if p, ok := pointerOf(k); ok {
+		return h.mix(p)
+	}
+	return h.value(reflect.ValueOf(k))
+}
+
+// mix returns the seeded hash of a fixed-size value. Like strings, it is
+// hashed with maphash so that collisions do not depend on the value only.
+func (h *hasher) mix(v uint64) uint64 {
+	if !h.seeded {
+		return fmix64(v)
+	}
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	var mh maphash.Hash
+	mh.SetSeed(h.seed)
+	mh.Write(b[:])
+	return mh.Sum64()
+}
+
+// str returns the seeded hash of a string.
+func (h *hasher) str(s string) uint64 {
+	if !h.seeded {
+		return hashString(s)
+	}
+	var mh maphash.Hash
+	mh.SetSeed(h.seed)
+	mh.WriteString(s)
+	return mh.Sum64()
+}
+
+func (h *hasher) complex(c complex128) uint64 {
+	return combine(h.mix(math.Float64bits(real(c))), h.mix(math.Float64bits(imag(c))))
+}
+
+// value returns hash of a value of any comparable type, such as structs
+// and arrays, by walking its elements.
+func (h *hasher) value(v reflect.Value) uint64 {
+	if !v.IsValid() {
+		return 0
+	}
+	return typeHasher(v.Type())(h, v)
+}
+
+// combine returns hash of a sequence of two hash values.
+func combine(a, b uint64) uint64 {
+	return (bits.RotateLeft64(a, 31) ^ b) * 0x9e3779b97f4a7c15
+}
+
+// fmix64 is the finalizer of MurmurHash3, which is a bijection so
+// different values never collide.
+func fmix64(v uint64) uint64 {
+	v ^= v >> 33
+	v *= 0xff51afd7ed558ccd
+	v ^= v >> 33
+	v *= 0xc4ceb9fe1a85ec53
+	v ^= v >> 33
+	return v
+}
+
+// spread returns upper bits of the mixed hash h, which select shards and
+// read buffers while lower bits of h select cache segments. Hashes are mixed
+// first as those returned by WithHasher may only have 32 bits.
+func spread(h uint64) uint64 {
+	return fmix64(h) >> 32
+}
+
+// sum calculates deterministic hash value of the given key.
 func sum(k interface{}) uint64 {
 	switch h := k.(type) {
 	case Hash:
@@ -39,23 +175,26 @@ func sum(k interface{}) uint64 {
 	case uintptr:
 		return hashU64(uint64(h))
 	case float32:
-		return hashU32(math.Float32bits(h))
+		return hashU32(math.Float32bits(normalizeFloat32(h)))
 	case float64:
-		return hashU64(math.Float64bits(h))
+		return hashU64(math.Float64bits(normalizeFloat64(h)))
 	case bool:
 		if h {
 			return 1
 		}
 		return 0
+	case complex64:
+		return combine(hashU32(math.Float32bits(real(h))), hashU32(math.Float32bits(imag(h))))
+	case complex128:
+		return combine(hashU64(math.Float64bits(real(h))), hashU64(math.Float64bits(imag(h))))
 	case string:
 		return hashString(h)
 	}
-	// TODO: complex64 and complex128
-	if h, ok := hashPointer(k); ok {
-		return h
+	if p, ok := pointerOf(k); ok {
+		return hashU64(p)
 	}
-	// TODO: use gob to encode k to bytes then hash.
-	return 0
+	var fixed hasher
+	return fixed.value(reflect.ValueOf(k))
 }
 
 const (
@@ -113,12 +252,121 @@ func hashString(data string) uint64 {
 	return h
 }
 
-func hashPointer(k interface{}) (uint64, bool) {
+// normalizeFloat32 returns +0 for -0 as they are equal keys.
+func normalizeFloat32(f float32) float32 {
+	if f == 0 {
+		return 0
+	}
+	return f
+}
+
+// normalizeFloat64 returns +0 for -0 as they are equal keys.
+func normalizeFloat64(f float64) float64 {
+	if f == 0 {
+		return 0
+	}
+	return f
+}
+
+// pointerOf returns the pointer value if k is a pointer.
+func pointerOf(k interface{}) (uint64, bool) {
 	v := reflect.ValueOf(k)
 	switch v.Kind() {
 	case reflect.Ptr, reflect.UnsafePointer, reflect.Func, reflect.Slice, reflect.Map, reflect.Chan:
-		return hashU64(uint64(v.Pointer())), true
+		return uint64(v.Pointer()), true
 	default:
 		return 0, false
 	}
 }
+
+// valueHasher calculates hash of a reflected value of a specific type.
+type valueHasher func(h *hasher, v reflect.Value) uint64
+
+// valueHashers caches valueHasher of each type.
+var valueHashers sync.Map // map[reflect.Type]valueHasher
+
+// typeHasher returns valueHasher for type t.
+func typeHasher(t reflect.Type) valueHasher {
+	if f, ok := valueHashers.Load(t); ok {
+		return f.(valueHasher)
+	}
+	f := newTypeHasher(t)
+	valueHashers.Store(t, f)
+	return f
+}
+
+func newTypeHasher(t reflect.Type) valueHasher {
+	switch t.Kind() {
+	case reflect.Bool:
+		return func(h *hasher, v reflect.Value) uint64 {
+			if v.Bool() {
+				return h.mix(1)
+			}
+			return h.mix(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(h *hasher, v reflect.Value) uint64 {
+			return h.mix(uint64(v.Int()))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(h *hasher, v reflect.Value) uint64 {
+			return h.mix(v.Uint())
+		}
+	case reflect.Float32, reflect.Float64:
+		return func(h *hasher, v reflect.Value) uint64 {
+			return h.mix(math.Float64bits(normalizeFloat64(v.Float())))
+		}
+	case reflect.Complex64, reflect.Complex128:
+		return func(h *hasher, v reflect.Value) uint64 {
+			return h.complex(v.Complex())
+		}
+	case reflect.String:
+		return func(h *hasher, v reflect.Value) uint64 {
+			return h.str(v.String())
+		}
+	case reflect.Ptr, reflect.UnsafePointer, reflect.Chan:
+		return func(h *hasher, v reflect.Value) uint64 {
+			return h.mix(uint64(v.Pointer()))
+		}
+	case reflect.Interface:
+		return func(h *hasher, v reflect.Value) uint64 {
+			if v.IsNil() {
+				return 0
+			}
+			return h.value(v.Elem())
+		}
+	case reflect.Array:
+		n := t.Len()
+		elem := typeHasher(t.Elem())
+		return func(h *hasher, v reflect.Value) uint64 {
+			acc := uint64(n)
+			for i := 0; i < n; i++ {
+				acc = combine(acc, elem(h, v.Index(i)))
+			}
+			return acc
+		}
+	case reflect.Struct:
+		var indexes []int
+		var fields []valueHasher
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).Name == "_" {
+				// Blank fields are not compared.
+				continue
+			}
+			indexes = append(indexes, i)
+			fields = append(fields, typeHasher(t.Field(i).Type))
+		}
+		return func(h *hasher, v reflect.Value) uint64 {
+			var acc uint64
+			for i, f := range fields {
+				acc = combine(acc, f(h, v.Field(indexes[i])))
+			}
+			return acc
+		}
+	default:
+		// Other types are not comparable.
+		return func(h *hasher, v reflect.Value) uint64 {
+			return 0
+		}
+	}
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"encoding/binary"
 	"hash/fnv"
+	"math"
 	"testing"
 	"unsafe"
 )
@@ -51,6 +52,8 @@ func TestSum(t *testing.T) {
 		{"string", sumFNV([]byte("string"))},
 		{t, sumFNVu64(uint64(uintptr(unsafe.Pointer(t))))},
 		{(*testing.T)(nil), sumFNVu64(0)},
+		{complex(float32(1), float32(2)), combine(sumFNVu32(0x3f800000), sumFNVu32(0x40000000))},
+		{complex(1, 2), combine(sumFNVu64(0x3ff0000000000000), sumFNVu64(0x4000000000000000))},
 	}
 
 	for _, tt := range tests {
@@ -62,6 +65,108 @@ func TestSum(t *testing.T) {
 	}
 }
 
+func TestHasher(t *testing.T) {
+	var h1, h2 hasher
+	h1.init()
+	h2.init()
+	keys := []interface{}{int(-1), int8(8), uint32(32), uint64(64), float64(2.5), true, "", "string", t}
+	for _, k := range keys {
+		if h1.sum(k) != h1.sum(k) {
+			t.Errorf("unexpected hash: %v, key: %+v (%T)", h1.sum(k), k, k)
+		}
+		if h1.sum(k) == h2.sum(k) {
+			t.Errorf("unexpected same hash: %v, key: %+v (%T)", h1.sum(k), k, k)
+		}
+	}
+	if h1.sum(hashKey(1)) != 1 {
+		t.Errorf("unexpected hash: %v", h1.sum(hashKey(1)))
+	}
+	seen := make(map[uint64]bool)
+	for i := 0; i < 1000; i++ {
+		v := h1.sum(i)
+		if seen[v] {
+			t.Fatalf("unexpected collision: %v, key: %v", v, i)
+		}
+		seen[v] = true
+	}
+	// -0 and +0 are equal keys.
+	negZero := math.Copysign(0, -1)
+	if h1.sum(negZero) != h1.sum(0.0) || h1.sum(float32(negZero)) != h1.sum(float32(0)) ||
+		sum(negZero) != sum(0.0) || sum(float32(negZero)) != sum(float32(0)) {
+		t.Errorf("unexpected hash of negative zero: %v", h1.sum(negZero))
+	}
+	// Fixed-size values with the same difference do not collide across seeds.
+	if h1.sum(1)^h1.sum(2) == h2.sum(1)^h2.sum(2) {
+		t.Errorf("unexpected seed-independent difference: %v", h1.sum(1)^h1.sum(2))
+	}
+	// Zero value is deterministic.
+	var h hasher
+	if h.sum("string") != sum("string") {
+		t.Errorf("unexpected hash: %v", h.sum("string"))
+	}
+}
+
+func TestSumStruct(t *testing.T) {
+	type tenantKey struct {
+		Tenant string
+		ID     int
+		_      int
+		tags   [2]interface{}
+		ratio  float64
+	}
+	var seeded hasher
+	seeded.init()
+	for _, h := range []*hasher{{}, &seeded} {
+		k1 := tenantKey{Tenant: "a", ID: 1, tags: [2]interface{}{"x", 1.5}}
+		k2 := k1
+		k2.ratio = math.Copysign(0, -1)
+		if h.sum(k1) != h.sum(k2) {
+			t.Errorf("unexpected different hash: %v, %v", h.sum(k1), h.sum(k2))
+		}
+		seen := make(map[uint64]interface{})
+		keys := []interface{}{
+			k1,
+			tenantKey{Tenant: "a", ID: 2},
+			tenantKey{Tenant: "b", ID: 1},
+			tenantKey{Tenant: "a", ID: 1, tags: [2]interface{}{"x", 2.5}},
+			tenantKey{Tenant: "a", ID: 1, tags: [2]interface{}{1.5, "x"}},
+			[2]int{1, 2},
+			[2]int{2, 1},
+			complex(1, 2),
+			complex(2, 1),
+		}
+		for _, k := range keys {
+			v := h.sum(k)
+			if ek, ok := seen[v]; ok {
+				t.Errorf("unexpected collision: %v, keys: %+v, %+v", v, k, ek)
+			}
+			seen[v] = k
+		}
+	}
+}
+
+func TestWithHasher(t *testing.T) {
+	c := New(WithHasher(func(k Key) uint64 {
+		return uint64(k.(int) % 2)
+	}))
+	defer c.Close()
+	for i := 0; i < 10; i++ {
+		c.Put(i, i)
+	}
+	for i := 0; i < 10; i++ {
+		v, ok := c.GetIfPresent(i)
+		if !ok || v.(int) != i {
+			t.Fatalf("unexpected value: %v (%v), want: %v", v, ok, i)
+		}
+	}
+}
+
+type hashKey uint64
+
+func (k hashKey) Sum64() uint64 {
+	return uint64(k)
+}
+
 func BenchmarkSumInt(b *testing.B) {
 	b.ReportAllocs()
 	b.RunParallel(func(pb *testing.PB) {
@@ -71,6 +176,28 @@ func BenchmarkSumInt(b *testing.B) {
 	})
 }
 
+func BenchmarkHasherInt(b *testing.B) {
+	var h hasher
+	h.init()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			h.sum(0x0105)
+		}
+	})
+}
+
+func BenchmarkHasherString(b *testing.B) {
+	var h hasher
+	h.init()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			h.sum("09130105060103210913010506010321091301050601032109130105060103210913010506010321")
+		}
+	})
+}
+
 func BenchmarkSumString(b *testing.B) {
 	b.ReportAllocs()
 	b.RunParallel(func(pb *testing.PB) {
@@ -79,3 +206,18 @@ func BenchmarkSumString(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkSumStruct(b *testing.B) {
+	type key struct {
+		Tenant string
+		ID     int
+	}
+	var h hasher
+	h.init()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			h.sum(key{"tenant", 1})
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+)
+
+// Invalidation is a message for invalidating entries in other caches.
+type Invalidation struct {
+	// Origin identifies the cache which published this message.
+	Origin string
+	// Key is the key to be invalidated when All is false.
+	Key Key
+	// All is set when all entries are invalidated.
+	All bool
+}
+
+// InvalidationBus delivers invalidation messages among caches, usually in
+// different processes, so that an invalidation in a cache is also applied
+// to the others.
+type InvalidationBus interface {
+	// Publish sends the invalidation message to all subscribers.
+	Publish(Invalidation) error
+	// Subscribe registers fn to be called for each invalidation message
+	// received, including messages published by the same subscriber.
+	// It returns a function to cancel the subscription.
+	Subscribe(fn func(Invalidation)) (cancel func())
+}
+
+// subscribers is a set of invalidation message handlers.
+type subscribers struct {
+	mu     sync.RWMutex
+	nextID int
+	fns    map[int]func(Invalidation)
+}
+
+func (s *subscribers) add(fn func(Invalidation)) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fns == nil {
+		s.fns = make(map[int]func(Invalidation))
+	}
+	id := s.nextID
+	s.nextID++
+	s.fns[id] = fn
+	return func() {
+		s.mu.Lock()
+		delete(s.fns, id)
+		s.mu.Unlock()
+	}
+}
+
+func (s *subscribers) dispatch(m Invalidation) {
+	s.mu.RLock()
+	fns := make([]func(Invalidation), 0, len(s.fns))
+	for _, fn := range s.fns {
+		fns = append(fns, fn)
+	}
+	s.mu.RUnlock()
+	for _, fn := range fns {
+		fn(m)
+	}
+}
+
+// memoryBus is an in-process InvalidationBus.
+type memoryBus struct {
+	subs subscribers
+}
+
+// NewMemoryInvalidationBus returns an InvalidationBus which delivers messages
+// synchronously to subscribers in the same process.
+// It is mostly useful for testing.
+func NewMemoryInvalidationBus() InvalidationBus {
+	return &memoryBus{}
+}
+
+// Publish calls all subscribers with the message.
+func (b *memoryBus) Publish(m Invalidation) error {
+	b.subs.dispatch(m)
+	return nil
+}
+
+// Subscribe adds fn to the subscribers.
+func (b *memoryBus) Subscribe(fn func(Invalidation)) func() {
+	return b.subs.add(fn)
+}
+
+// newBusID returns a random identity of a cache on an invalidation bus.
+func newBusID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
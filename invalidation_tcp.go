@@ -0,0 +1,252 @@
+package cache
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	tcpBusTimeout = 5 * time.Second
+	// Maximum number of messages queued for each peer.
+	tcpBusQueueSize = 1024
+	// Delays between reconnection attempts to an unreachable peer.
+	tcpBusMinBackoff = 100 * time.Millisecond
+	tcpBusMaxBackoff = 10 * time.Second
+)
+
+var errBusClosed = errors.New("cache: invalidation bus closed")
+
+// tcpBusPeer is a queue of messages sent to a peer by its own goroutine.
+type tcpBusPeer struct {
+	addr   string
+	queue  chan Invalidation
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// TCPInvalidationBus is an InvalidationBus which sends messages to all
+// peers over TCP. Messages are encoded using encoding/gob, so custom key
+// types must be registered with gob.Register.
+// Each peer has a bounded queue of messages, which are dropped when the
+// queue is full, e.g. while the peer is unreachable.
+type TCPInvalidationBus struct {
+	ln   net.Listener
+	subs subscribers
+	// number of messages dropped
+	dropped uint64
+
+	mu    sync.Mutex
+	peers map[string]*tcpBusPeer
+	// incoming connections
+	accepted map[net.Conn]struct{}
+	closed   bool
+
+	wg sync.WaitGroup
+}
+
+// NewTCPInvalidationBus returns a TCPInvalidationBus listening on addr
+// and sending messages to peers, which are addresses of other buses.
+func NewTCPInvalidationBus(addr string, peers ...string) (*TCPInvalidationBus, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	b := &TCPInvalidationBus{
+		ln:       ln,
+		peers:    make(map[string]*tcpBusPeer),
+		accepted: make(map[net.Conn]struct{}),
+	}
+	b.SetPeers(peers...)
+	b.wg.Add(1)
+	go b.serve()
+	return b, nil
+}
+
+// Addr returns the listening address of this bus.
+func (b *TCPInvalidationBus) Addr() net.Addr {
+	return b.ln.Addr()
+}
+
+// SetPeers replaces addresses of peers. Messages queued for removed peers
+// are dropped.
+func (b *TCPInvalidationBus) SetPeers(peers ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	keep := make(map[string]bool, len(peers))
+	for _, addr := range peers {
+		keep[addr] = true
+		if b.peers[addr] == nil {
+			ctx, cancel := context.WithCancel(context.Background())
+			p := &tcpBusPeer{
+				addr:   addr,
+				queue:  make(chan Invalidation, tcpBusQueueSize),
+				ctx:    ctx,
+				cancel: cancel,
+			}
+			b.peers[addr] = p
+			b.wg.Add(1)
+			go b.send(p)
+		}
+	}
+	for addr, p := range b.peers {
+		if !keep[addr] {
+			p.cancel()
+			delete(b.peers, addr)
+		}
+	}
+}
+
+// Publish dispatches the message to subscribers of this bus and queues it
+// for all peers without blocking. It returns an error only when the bus
+// is closed.
+func (b *TCPInvalidationBus) Publish(m Invalidation) error {
+	b.subs.dispatch(m)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return errBusClosed
+	}
+	for _, p := range b.peers {
+		select {
+		case p.queue <- m:
+		default:
+			atomic.AddUint64(&b.dropped, 1)
+		}
+	}
+	return nil
+}
+
+// Dropped returns the number of messages dropped because queues of peers
+// were full.
+func (b *TCPInvalidationBus) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+// Subscribe adds fn to the subscribers.
+func (b *TCPInvalidationBus) Subscribe(fn func(Invalidation)) func() {
+	return b.subs.add(fn)
+}
+
+// Close stops listening and closes all connections.
+func (b *TCPInvalidationBus) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	err := b.ln.Close()
+	for addr, p := range b.peers {
+		p.cancel()
+		delete(b.peers, addr)
+	}
+	for conn := range b.accepted {
+		conn.Close()
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+	return err
+}
+
+// send encodes queued messages to the peer until it is removed, connecting
+// to the peer when needed and backing off while it is unreachable.
+func (b *TCPInvalidationBus) send(p *tcpBusPeer) {
+	defer b.wg.Done()
+	var (
+		dialer  net.Dialer
+		conn    net.Conn
+		enc     *gob.Encoder
+		backoff time.Duration
+	)
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	for {
+		var m Invalidation
+		select {
+		case <-p.ctx.Done():
+			return
+		case m = <-p.queue:
+		}
+		for conn == nil {
+			ctx, cancel := context.WithTimeout(p.ctx, tcpBusTimeout)
+			c, err := dialer.DialContext(ctx, "tcp", p.addr)
+			cancel()
+			if err == nil {
+				conn, enc = c, gob.NewEncoder(c)
+				backoff = 0
+				break
+			}
+			if backoff < tcpBusMinBackoff {
+				backoff = tcpBusMinBackoff
+			} else if backoff *= 2; backoff > tcpBusMaxBackoff {
+				backoff = tcpBusMaxBackoff
+			}
+			t := time.NewTimer(backoff)
+			select {
+			case <-p.ctx.Done():
+				t.Stop()
+				return
+			case <-t.C:
+			}
+		}
+		conn.SetWriteDeadline(time.Now().Add(tcpBusTimeout))
+		if err := enc.Encode(&m); err != nil {
+			// The message is dropped and the connection is reopened
+			// for the next one.
+			atomic.AddUint64(&b.dropped, 1)
+			conn.Close()
+			conn = nil
+		}
+	}
+}
+
+func (b *TCPInvalidationBus) serve() {
+	defer b.wg.Done()
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			conn.Close()
+			return
+		}
+		b.accepted[conn] = struct{}{}
+		b.mu.Unlock()
+
+		b.wg.Add(1)
+		go b.receive(conn)
+	}
+}
+
+func (b *TCPInvalidationBus) receive(conn net.Conn) {
+	defer b.wg.Done()
+	defer func() {
+		b.mu.Lock()
+		delete(b.accepted, conn)
+		b.mu.Unlock()
+		conn.Close()
+	}()
+	dec := gob.NewDecoder(conn)
+	for {
+		var m Invalidation
+		if err := dec.Decode(&m); err != nil {
+			return
+		}
+		b.subs.dispatch(m)
+	}
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryInvalidationBus(t *testing.T) {
+	bus := NewMemoryInvalidationBus()
+	wg := sync.WaitGroup{}
+	remFunc := func(Key, Value) {
+		wg.Done()
+	}
+	c1 := New(WithInvalidationBus(bus), WithRemovalListener(remFunc))
+	defer c1.Close()
+	c2 := New(WithInvalidationBus(bus), WithRemovalListener(remFunc))
+	defer c2.Close()
+
+	for _, c := range []Cache{c1, c2} {
+		c.Put(1, 1)
+		c.Put(2, 2)
+	}
+	wg.Add(2)
+	c1.Invalidate(1)
+	wg.Wait()
+	for _, c := range []Cache{c1, c2} {
+		_, ok := c.GetIfPresent(1)
+		if ok {
+			t.Fatalf("unexpected value for %v", 1)
+		}
+		v, ok := c.GetIfPresent(2)
+		if !ok || v.(int) != 2 {
+			t.Fatalf("unexpected value: %v (%v)", v, ok)
+		}
+	}
+	wg.Add(2)
+	c2.InvalidateAll()
+	wg.Wait()
+	for _, c := range []Cache{c1, c2} {
+		_, ok := c.GetIfPresent(2)
+		if ok {
+			t.Fatalf("unexpected value for %v", 2)
+		}
+	}
+}
+
+func TestMemoryInvalidationBusEcho(t *testing.T) {
+	bus := NewMemoryInvalidationBus()
+	var received []Invalidation
+	cancel := bus.Subscribe(func(m Invalidation) {
+		received = append(received, m)
+	})
+	c := New(WithInvalidationBus(bus))
+	defer c.Close()
+	c.Invalidate("k")
+	c.InvalidateAll()
+	if len(received) != 2 || received[0].Key != "k" || !received[1].All {
+		t.Fatalf("unexpected messages: %+v", received)
+	}
+	cancel()
+	c.Invalidate("k")
+	if len(received) != 2 {
+		t.Fatalf("unexpected messages: %+v", received)
+	}
+}
+
+func TestTCPInvalidationBus(t *testing.T) {
+	b1, err := NewTCPInvalidationBus("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b1.Close()
+	b2, err := NewTCPInvalidationBus("127.0.0.1:0", b1.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b2.Close()
+	b1.SetPeers(b2.Addr().String())
+
+	received := make(chan Invalidation, 10)
+	b2.Subscribe(func(m Invalidation) {
+		received <- m
+	})
+	if err = b1.Publish(Invalidation{Origin: "1", Key: "k"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = b1.Publish(Invalidation{Origin: "1", All: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []Invalidation{{Origin: "1", Key: "k"}, {Origin: "1", All: true}} {
+		select {
+		case m := <-received:
+			if m != want {
+				t.Fatalf("unexpected message: %+v, want: %+v", m, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("message not received: %+v", want)
+		}
+	}
+
+	c1 := New(WithInvalidationBus(b1))
+	defer c1.Close()
+	c2 := New(WithInvalidationBus(b2))
+	defer c2.Close()
+	c2.Put(1, 1)
+	c1.Invalidate(1)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := c2.GetIfPresent(1); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("entry is not invalidated")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTCPInvalidationBusUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	b, err := NewTCPInvalidationBus("127.0.0.1:0", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i := 0; i < tcpBusQueueSize+10; i++ {
+		if err = b.Publish(Invalidation{Origin: "1", Key: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("publish is blocked: %v", d)
+	}
+	if b.Dropped() == 0 {
+		t.Fatal("expected dropped messages")
+	}
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Publish(Invalidation{Origin: "1", Key: 1}); err != errBusClosed {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
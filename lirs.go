@@ -0,0 +1,308 @@
+package cache
+
+import (
+	"container/list"
+)
+
+const (
+	lirsLIR uint8 = iota
+	lirsHIR
+	lirsNonResident
+)
+
+const (
+	// Ratio of the capacity for resident HIR entries.
+	lirsHIRRatio = 0.01
+	// Maximum number of non-resident HIR entries relative to the capacity.
+	lirsNonResidentMultiplier = 1
+)
+
+// lirsNode is the state of a key in LIRS. Non-resident nodes do not have entry.
+type lirsNode struct {
+	key    Key
+	en     *entry
+	status uint8
+
+	// Elements of the stack, the queue and the non-resident list.
+	s *list.Element
+	q *list.Element
+	g *list.Element
+}
+
+// lirsCache is a Low Inter-reference Recency Set cache.
+// Entries with low inter-reference recency (LIR) are kept in the stack S,
+// which also holds recently accessed HIR entries, including non-resident ones.
+// Resident HIR entries are kept in the queue Q and evicted first.
+// See http://web.cse.ohio-state.edu/hpcs/WWW/HTML/publications/papers/TR-02-6.pdf
+//
+// Entry accessList points to its element in S if the entry is LIR, otherwise
+// its element in Q.
+type lirsCache struct {
+	cache *cache
+	cap   int
+
+	lirCap   int
+	lirCount int
+
+	nodes map[Key]*lirsNode
+	s     list.List // Top of the stack is the front.
+	q     list.List // Front of the queue is evicted first.
+	g     list.List // Non-resident nodes in order of eviction.
+}
+
+// init initializes the stack and queue.
+func (l *lirsCache) init(c *cache, cap int) {
+	l.cache = c
+	l.cap = cap
+	if cap > 0 {
+		hirCap := int(float64(cap) * lirsHIRRatio)
+		if hirCap < 1 {
+			hirCap = 1
+		}
+		l.lirCap = cap - hirCap
+	}
+	l.lirCount = 0
+	l.nodes = make(map[Key]*lirsNode)
+	l.s.Init()
+	l.q.Init()
+	l.g.Init()
+}
+
+// length returns number of resident entries.
+func (l *lirsCache) length() int {
+	return l.lirCount + l.q.Len()
+}
+
+// write adds new entry to the cache and returns evicted entry if necessary.
+func (l *lirsCache) write(en *entry) *entry {
+	// Fast path
+	if en.accessList != nil {
+		// Entry existed, update its status instead.
+		l.markAccess(getLIRSNode(en))
+		return nil
+	}
+	cen := l.cache.getOrSet(en)
+	if cen != nil {
+		// Entry has already been added, update its value instead.
+		cen.setValue(en.getValue())
+		cen.setWriteTime(en.getWriteTime())
+		if cen.accessList != nil {
+			l.markAccess(getLIRSNode(cen))
+			return nil
+		}
+		// Entry is loaded to the cache but not yet registered.
+		en = cen
+	}
+	var ren *entry
+	if l.cap > 0 && l.length() >= l.cap {
+		ren = l.evict()
+	}
+	n := l.nodes[en.key]
+	if n == nil {
+		n = &lirsNode{key: en.key, en: en}
+		l.nodes[en.key] = n
+		n.s = l.s.PushFront(n)
+		if l.cap <= 0 || l.lirCount < l.lirCap {
+			// Cache is warming up.
+			n.status = lirsLIR
+			l.lirCount++
+		} else {
+			n.status = lirsHIR
+			n.q = l.q.PushBack(n)
+		}
+		l.link(n)
+		return ren
+	}
+	// Non-resident entry is in the stack so its recency is lower than
+	// the least recent LIR entry.
+	l.g.Remove(n.g)
+	n.g = nil
+	n.en = en
+	n.status = lirsLIR
+	l.s.MoveToFront(n.s)
+	l.lirCount++
+	l.link(n)
+	if l.lirCount > l.lirCap {
+		l.demote()
+	}
+	return ren
+}
+
+// evict removes the resident HIR entry at the front of the queue and
+// returns that entry.
+func (l *lirsCache) evict() *entry {
+	if l.q.Len() == 0 {
+		l.demote()
+	}
+	el := l.q.Front()
+	if el == nil {
+		return nil
+	}
+	n := el.Value.(*lirsNode)
+	l.q.Remove(el)
+	n.q = nil
+	en := n.en
+	l.cache.delete(en)
+	en.accessList = nil
+	n.en = nil
+	if n.s == nil {
+		delete(l.nodes, n.key)
+		return en
+	}
+	// Keep it in the stack as non-resident.
+	n.status = lirsNonResident
+	n.g = l.g.PushBack(n)
+	if l.g.Len() > lirsNonResidentMultiplier*l.cap {
+		l.removeNode(l.g.Front().Value.(*lirsNode))
+	}
+	return en
+}
+
+// demote moves the LIR entry at the bottom of the stack to the queue.
+func (l *lirsCache) demote() {
+	el := l.s.Back()
+	if el == nil {
+		return
+	}
+	n := el.Value.(*lirsNode)
+	l.s.Remove(el)
+	n.s = nil
+	n.status = lirsHIR
+	n.q = l.q.PushBack(n)
+	l.lirCount--
+	l.link(n)
+	l.prune()
+}
+
+// prune removes HIR entries at the bottom of the stack so that the bottom
+// is always a LIR entry.
+func (l *lirsCache) prune() {
+	for el := l.s.Back(); el != nil; el = l.s.Back() {
+		n := el.Value.(*lirsNode)
+		if n.status == lirsLIR {
+			return
+		}
+		l.s.Remove(el)
+		n.s = nil
+		if n.status == lirsNonResident {
+			l.g.Remove(n.g)
+			n.g = nil
+			delete(l.nodes, n.key)
+		}
+	}
+}
+
+// removeNode removes node n from all lists.
+func (l *lirsCache) removeNode(n *lirsNode) {
+	if n.s != nil {
+		l.s.Remove(n.s)
+		n.s = nil
+	}
+	if n.q != nil {
+		l.q.Remove(n.q)
+		n.q = nil
+	}
+	if n.g != nil {
+		l.g.Remove(n.g)
+		n.g = nil
+	}
+	delete(l.nodes, n.key)
+}
+
+// link updates list of the resident entry in node n.
+func (l *lirsCache) link(n *lirsNode) {
+	n.en.listID = n.status
+	if n.status == lirsLIR {
+		n.en.accessList = n.s
+	} else {
+		n.en.accessList = n.q
+	}
+}
+
+// access updates cache entry for a get.
+func (l *lirsCache) access(en *entry) {
+	if en.accessList != nil {
+		l.markAccess(getLIRSNode(en))
+	}
+}
+
+// markAccess moves the node to the top of the stack.
+func (l *lirsCache) markAccess(n *lirsNode) {
+	if n.status == lirsLIR {
+		bottom := n.s == l.s.Back()
+		l.s.MoveToFront(n.s)
+		if bottom {
+			l.prune()
+		}
+		return
+	}
+	if n.s == nil {
+		// Inter-reference recency is still high.
+		n.s = l.s.PushFront(n)
+		l.q.MoveToBack(n.q)
+		return
+	}
+	// Resident HIR entry in the stack becomes LIR.
+	l.q.Remove(n.q)
+	n.q = nil
+	n.status = lirsLIR
+	l.s.MoveToFront(n.s)
+	l.lirCount++
+	l.link(n)
+	if l.lirCount > l.lirCap {
+		l.demote()
+	}
+}
+
+// remove removes an entry from the cache and returns the removed entry or nil
+// if it is not found.
+func (l *lirsCache) remove(en *entry) *entry {
+	if en.accessList == nil {
+		return nil
+	}
+	n := getLIRSNode(en)
+	l.cache.delete(en)
+	if n.status == lirsLIR {
+		l.lirCount--
+	}
+	l.removeNode(n)
+	en.accessList = nil
+	n.en = nil
+	l.prune()
+	return en
+}
+
+// iterate walks through resident HIR entries then LIR entries.
+// fn may remove the given entry.
+func (l *lirsCache) iterate(fn func(en *entry) bool) {
+	for el := l.q.Front(); el != nil; {
+		next := el.Next()
+		if !fn(el.Value.(*lirsNode).en) {
+			return
+		}
+		el = next
+	}
+	for el := l.s.Back(); el != nil; {
+		n := el.Value.(*lirsNode)
+		if n.status != lirsLIR {
+			el = el.Prev()
+			continue
+		}
+		prev := el.Prev()
+		if !fn(n.en) {
+			return
+		}
+		if n.s == nil {
+			// Removing the entry prunes the stack, so its new bottom is
+			// the next LIR entry.
+			el = l.s.Back()
+		} else {
+			el = prev
+		}
+	}
+}
+
+// getLIRSNode returns the node of the resident entry.
+func getLIRSNode(en *entry) *lirsNode {
+	return en.accessList.Value.(*lirsNode)
+}
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"testing"
+)
+
+type lirsTest struct {
+	c    cache
+	lirs lirsCache
+	t    *testing.T
+}
+
+func (t *lirsTest) assertLen(lir, hir, nonResident, stack int) {
+	sz := cacheSize(&t.c)
+	if sz != lir+hir || t.lirs.lirCount != lir || t.lirs.q.Len() != hir ||
+		t.lirs.g.Len() != nonResident || t.lirs.s.Len() != stack {
+		t.t.Helper()
+		t.t.Fatalf("unexpected data length: cache=%d lir=%d hir=%d non-resident=%d stack=%d, want: %d %d %d %d",
+			sz, t.lirs.lirCount, t.lirs.q.Len(), t.lirs.g.Len(), t.lirs.s.Len(), lir, hir, nonResident, stack)
+	}
+}
+
+func (t *lirsTest) assertEntry(k string, id uint8) {
+	en := t.c.get(k, sum(k))
+	if en == nil || en.listID != id {
+		t.t.Helper()
+		t.t.Fatalf("unexpected entry: %+v, want: {key: %v, listID: %v}", en, k, id)
+	}
+}
+
+func (t *lirsTest) write(k string) *entry {
+	return t.lirs.write(newEntry(k, k, sum(k)))
+}
+
+func (t *lirsTest) access(k string) {
+	t.lirs.access(t.c.get(k, sum(k)))
+}
+
+func TestLIRS(t *testing.T) {
+	s := lirsTest{t: t}
+	s.c.init(defaultConcurrencyLevel)
+	s.lirs.init(&s.c, 3)
+
+	for _, k := range []string{"A", "B", "C"} {
+		if en := s.write(k); en != nil {
+			t.Fatalf("unexpected entry removed: %+v", en)
+		}
+	}
+	// S: C B A, Q: C
+	s.assertLen(2, 1, 0, 3)
+	s.assertEntry("A", lirsLIR)
+	s.assertEntry("C", lirsHIR)
+
+	en := s.write("D")
+	if en == nil || en.key != "C" {
+		t.Fatalf("unexpected entry removed: %+v", en)
+	}
+	// S: D C(non-resident) B A, Q: D
+	s.assertLen(2, 1, 1, 4)
+
+	en = s.write("C")
+	if en == nil || en.key != "D" {
+		t.Fatalf("unexpected entry removed: %+v", en)
+	}
+	// S: C D(non-resident) B, Q: A
+	s.assertLen(2, 1, 1, 3)
+	s.assertEntry("C", lirsLIR)
+	s.assertEntry("A", lirsHIR)
+
+	s.access("B")
+	// S: B C, Q: A
+	s.assertLen(2, 1, 0, 2)
+	s.access("A")
+	// S: A B C, Q: A
+	s.assertLen(2, 1, 0, 3)
+	s.access("A")
+	// S: A B, Q: C
+	s.assertLen(2, 1, 0, 2)
+	s.assertEntry("A", lirsLIR)
+	s.assertEntry("C", lirsHIR)
+
+	en = s.lirs.remove(s.c.get("B", sum("B")))
+	if en == nil || en.key != "B" {
+		t.Fatalf("unexpected entry removed: %+v", en)
+	}
+	// S: A, Q: C
+	s.assertLen(1, 1, 0, 1)
+	if en = s.write("E"); en != nil {
+		t.Fatalf("unexpected entry removed: %+v", en)
+	}
+	// S: E A, Q: C
+	s.assertLen(2, 1, 0, 2)
+
+	var keys []Key
+	s.lirs.iterate(func(en *entry) bool {
+		keys = append(keys, en.key)
+		return true
+	})
+	if len(keys) != 3 || keys[0] != "C" || keys[1] != "A" || keys[2] != "E" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+	s.lirs.iterate(func(en *entry) bool {
+		s.lirs.remove(en)
+		return true
+	})
+	s.assertLen(0, 0, 0, 0)
+}
+
+func TestLIRSIterate(t *testing.T) {
+	s := lirsTest{t: t}
+	s.c.init(defaultConcurrencyLevel)
+	s.lirs.init(&s.c, 4)
+	for _, k := range []string{"A", "B", "C", "D", "E", "C", "F", "D"} {
+		s.write(k)
+	}
+	// S: D F(non-resident) C E(non-resident) B
+	n := 0
+	allocs := testing.AllocsPerRun(10, func() {
+		n = 0
+		s.lirs.iterate(func(en *entry) bool {
+			n++
+			return n < 2
+		})
+	})
+	if n != 2 || allocs != 0 {
+		t.Fatalf("unexpected iteration: %d entries, %v allocs", n, allocs)
+	}
+	var keys []Key
+	s.lirs.iterate(func(en *entry) bool {
+		keys = append(keys, en.key)
+		s.lirs.remove(en)
+		return true
+	})
+	if len(keys) != 4 || cacheSize(&s.c) != 0 || s.lirs.length() != 0 {
+		t.Fatalf("unexpected removed keys: %v", keys)
+	}
+}
+
+func TestLIRSLoop(t *testing.T) {
+	// A loop larger than the cache size.
+	var keys []int
+	for i := 0; i < 100; i++ {
+		for k := 0; k < 120; k++ {
+			keys = append(keys, k)
+		}
+	}
+	lirs := replayPolicy(&lirsCache{}, keys)
+	lru := replayPolicy(&lruCache{}, keys)
+	if lirs <= lru {
+		t.Fatalf("unexpected hits: lirs=%d lru=%d", lirs, lru)
+	}
+}
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"io"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,6 +16,8 @@ const (
 	drainMax = 16
 	// Number of cache access operations that will trigger clean up.
 	drainThreshold = 64
+	// Interval of draining the read buffer when there are no writes.
+	drainInterval = time.Second
 )
 
 // currentTime is an alias for time.Now, used for testing.
@@ -24,30 +27,66 @@ var currentTime = time.Now
 type localCache struct {
 	// internal data structure
 	cache cache // Must be aligned on 32-bit
+	// hasher calculates hash of keys with a random seed of this cache.
+	hasher hasher
 
 	// user configurations
 	expireAfterAccess time.Duration
 	expireAfterWrite  time.Duration
 	refreshAfterWrite time.Duration
 	policyName        string
+	customPolicy      Policy
 
 	onInsertion Func
 	onRemoval   Func
+	// onEviction is called for entries evicted due to the size limit.
+	onEviction func(en *entry)
+	// spill is for Tiered cache to write evicted entries to the next level.
+	spill bool
 
 	loader   LoaderFunc
 	reloader Reloader
+	weigher  Weigher
 	stats    StatsCounter
+	// savedRecorder is set when stats supports recording load time saved.
+	savedRecorder loadTimeSavedRecorder
+	// shadowMultipliers enables hit rate estimation at these relative sizes.
+	shadowMultipliers []float64
+	// shadow is only set when hit rate estimation is enabled.
+	shadow *shadowCache
+	// traceWriter and traceRate configure the trace recorder of this cache.
+	traceWriter io.Writer
+	traceRate   float64
+	// recorder records sampled operations when it is set. It is shared by
+	// shards and only closed by the cache which created it.
+	recorder *traceRecorder
+
+	// bus delivers invalidations to and from other caches.
+	bus InvalidationBus
+	// busID is the identity of this cache on the bus.
+	busID string
+	// unsubscribe cancels the subscription to the bus.
+	unsubscribe func()
 
 	// cap is the cache capacity.
 	cap int
+	// concurrencyLevel is the number of cache data segments in power of two.
+	concurrencyLevel int
+	// shards is the number of independent caches when it is greater than one.
+	shards int
 
 	// accessQueue is the cache retention policy, which manages entries by access time.
 	accessQueue policy
 	// writeQueue is for managing entries by write time.
 	// It is only fulfilled when expireAfterWrite or refreshAfterWrite is set.
 	writeQueue policy
-	// events is the cache event queue for processEntries
+	// events is the bounded write buffer for processEntries.
 	events chan entryEvent
+	// readBuffer records accessed entries. Entries are dropped when it is
+	// full, so reads are never blocked.
+	readBuffer stripedBuffer
+	// drainSignal notifies processEntries to drain the read buffer.
+	drainSignal chan struct{}
 
 	// readCount is a counter of the number of reads since the last write.
 	readCount int32
@@ -61,15 +100,21 @@ type localCache struct {
 // init must be called before this cache can be used.
 func newLocalCache() *localCache {
 	return &localCache{
-		cap:   maximumCapacity,
-		cache: cache{},
-		stats: &statsCounter{},
+		cap:              maximumCapacity,
+		concurrencyLevel: defaultConcurrencyLevel,
+		stats:            &statsCounter{},
 	}
 }
 
 // init initializes cache replacement policy after all user configuration properties are set.
 func (c *localCache) init() {
-	c.accessQueue = newPolicy(c.policyName)
+	c.cache.init(c.concurrencyLevel)
+	c.hasher.init()
+	if c.customPolicy != nil {
+		c.accessQueue = &customPolicy{policy: c.customPolicy}
+	} else {
+		c.accessQueue = newPolicy(c.policyName)
+	}
 	c.accessQueue.init(&c.cache, c.cap)
 	if c.expireAfterWrite > 0 || c.refreshAfterWrite > 0 {
 		c.writeQueue = &recencyQueue{}
@@ -78,19 +123,44 @@ func (c *localCache) init() {
 	}
 	c.writeQueue.init(&c.cache, c.cap)
 	c.events = make(chan entryEvent, chanBufSize)
+	c.readBuffer.init()
+	c.drainSignal = make(chan struct{}, 1)
+	c.savedRecorder, _ = c.stats.(loadTimeSavedRecorder)
+	if len(c.shadowMultipliers) > 0 && c.cap > 0 {
+		c.shadow = &shadowCache{}
+		c.shadow.init(c.cap, c.shadowMultipliers)
+	}
+
+	if c.traceWriter != nil {
+		c.recorder = newTraceRecorder(c.traceWriter, c.traceRate)
+		c.recorder.start()
+	}
 
 	c.closeWG.Add(1)
 	go c.processEntries()
+
+	if c.bus != nil {
+		if c.busID == "" {
+			c.busID = newBusID()
+		}
+		c.unsubscribe = c.bus.Subscribe(c.onInvalidation)
+	}
 }
 
 // Close implements io.Closer and always returns a nil error.
 // Caller would ensure the cache is not being used (reading and writing) before closing.
 func (c *localCache) Close() error {
 	if atomic.CompareAndSwapInt32(&c.closing, 0, 1) {
+		if c.unsubscribe != nil {
+			c.unsubscribe()
+		}
 		// Do not close events channel to avoid panic when cache is still being used.
 		c.events <- entryEvent{nil, eventClose}
 		// Wait for the goroutine to close this channel
 		c.closeWG.Wait()
+		if c.traceWriter != nil {
+			c.recorder.close()
+		}
 	}
 	return nil
 }
@@ -98,32 +168,38 @@ func (c *localCache) Close() error {
 // GetIfPresent gets cached value from entries list and updates
 // last access time for the entry if it is found.
 func (c *localCache) GetIfPresent(k Key) (Value, bool) {
-	en := c.cache.get(k, sum(k))
+	h := c.hasher.sum(k)
+	c.recordRequest(h)
+	c.recordTrace(TraceGetIfPresent, h)
+	en := c.cache.get(k, h)
 	if en == nil {
 		c.stats.RecordMisses(1)
 		return nil, false
 	}
 	now := currentTime()
 	if c.isExpired(en, now) {
-		c.sendEvent(eventDelete, en)
+		// The expired entry is removed when the read buffer is drained.
+		c.recordAccess(en)
 		c.stats.RecordMisses(1)
 		return nil, false
 	}
 	c.setEntryAccessTime(en, now)
-	c.sendEvent(eventAccess, en)
-	c.stats.RecordHits(1)
+	c.recordAccess(en)
+	c.recordHit(en)
 	return en.getValue(), true
 }
 
 // Put adds new entry to entries list.
 func (c *localCache) Put(k Key, v Value) {
-	h := sum(k)
+	h := c.hasher.sum(k)
+	c.recordTrace(TracePut, h)
 	en := c.cache.get(k, h)
 	now := currentTime()
 	if en == nil {
 		en = newEntry(k, v, h)
 		c.setEntryWriteTime(en, now)
 		c.setEntryAccessTime(en, now)
+		c.setEntryWeight(en, v)
 		// Add to the cache directly so the new value is available immediately.
 		// However, only do this within the cache capacity (approximately).
 		if c.cap == 0 || c.cache.len() < c.cap {
@@ -131,6 +207,7 @@ func (c *localCache) Put(k Key, v Value) {
 			if cen != nil {
 				cen.setValue(v)
 				c.setEntryWriteTime(cen, now)
+				c.setEntryWeight(cen, v)
 				en = cen
 			}
 		}
@@ -138,32 +215,65 @@ func (c *localCache) Put(k Key, v Value) {
 		// Update value and send notice
 		en.setValue(v)
 		c.setEntryWriteTime(en, now)
+		c.setEntryWeight(en, v)
 	}
 	c.sendEvent(eventWrite, en)
 }
 
 // Invalidate removes the entry associated with key k.
 func (c *localCache) Invalidate(k Key) {
-	en := c.cache.get(k, sum(k))
+	h := c.hasher.sum(k)
+	c.recordTrace(TraceInvalidate, h)
+	c.invalidate(k, h)
+	if c.bus != nil {
+		c.bus.Publish(Invalidation{Origin: c.busID, Key: k})
+	}
+}
+
+// InvalidateAll resets entries list.
+func (c *localCache) InvalidateAll() {
+	c.invalidateAll()
+	if c.bus != nil {
+		c.bus.Publish(Invalidation{Origin: c.busID, All: true})
+	}
+}
+
+func (c *localCache) invalidate(k Key, h uint64) {
+	en := c.cache.get(k, h)
 	if en != nil {
 		en.setInvalidated(true)
 		c.sendEvent(eventDelete, en)
 	}
 }
 
-// InvalidateAll resets entries list.
-func (c *localCache) InvalidateAll() {
+func (c *localCache) invalidateAll() {
 	c.cache.walk(func(en *entry) {
 		en.setInvalidated(true)
 	})
 	c.sendEvent(eventDelete, nil)
 }
 
+// onInvalidation applies invalidations received from the bus, except those
+// published by this cache.
+func (c *localCache) onInvalidation(m Invalidation) {
+	if m.Origin == c.busID {
+		return
+	}
+	if m.All {
+		c.invalidateAll()
+	} else {
+		c.invalidate(m.Key, c.hasher.sum(m.Key))
+	}
+}
+
 // Get returns value associated with k or call underlying loader to retrieve value
 // if it is not in the cache. The returned value is only cached when loader returns
 // nil error.
 func (c *localCache) Get(k Key) (Value, error) {
-	en := c.cache.get(k, sum(k))
+	h := c.hasher.sum(k)
+	c.recordRequest(h)
+	c.recordTrace(TraceGet, h)
+	en := c.cache.get(k, h)
 	if en == nil {
 		c.stats.RecordMisses(1)
 		return c.load(k)
@@ -172,7 +282,7 @@ func (c *localCache) Get(k Key) (Value, error) {
 	now := currentTime()
 	if c.isExpired(en, now) {
 		if c.loader == nil {
-			c.sendEvent(eventDelete, en)
+			c.recordAccess(en)
 		} else {
 			// For loading cache, we do not delete entry but leave it to
 			// the eviction policy, so users still can get the old value.
@@ -182,8 +292,8 @@ func (c *localCache) Get(k Key) (Value, error) {
 		c.stats.RecordMisses(1)
 	} else {
 		c.setEntryAccessTime(en, now)
-		c.sendEvent(eventAccess, en)
-		c.stats.RecordHits(1)
+		c.recordAccess(en)
+		c.recordHit(en)
 	}
 	return en.getValue(), nil
 }
@@ -194,7 +304,7 @@ func (c *localCache) Refresh(k Key) {
 	if c.loader == nil {
 		return
 	}
-	en := c.cache.get(k, sum(k))
+	en := c.cache.get(k, c.hasher.sum(k))
 	if en == nil {
 		c.load(k)
 	} else {
@@ -207,34 +317,88 @@ func (c *localCache) Stats(t *Stats) {
 	c.stats.Snapshot(t)
 }
 
+// EstimateHitRates implements HitRateEstimator.
+func (c *localCache) EstimateHitRates() []HitRateEstimate {
+	if c.shadow == nil {
+		return nil
+	}
+	return c.shadow.estimates()
+}
+
 func (c *localCache) processEntries() {
 	defer c.closeWG.Done()
-	for e := range c.events {
-		switch e.event {
-		case eventWrite:
-			c.write(e.entry)
-			c.postWriteCleanup()
-		case eventAccess:
-			c.access(e.entry)
-			c.postReadCleanup()
-		case eventDelete:
-			if e.entry == nil {
+	// Accesses which do not fill the read buffer are applied periodically
+	// in read-only workloads.
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case e := <-c.events:
+			// Apply pending reads before the write.
+			c.drainReadBuffer()
+			switch e.event {
+			case eventWrite:
+				c.write(e.entry)
+				c.postWriteCleanup()
+			case eventDelete:
+				if e.entry == nil {
+					c.removeAll()
+				} else {
+					c.remove(e.entry)
+				}
+				c.postReadCleanup(1)
+			case eventClose:
+				if c.reloader != nil {
+					// Stop all refresh tasks.
+					c.reloader.Close()
+				}
 				c.removeAll()
-			} else {
-				c.remove(e.entry)
-			}
-			c.postReadCleanup()
-		case eventClose:
-			if c.reloader != nil {
-				// Stop all refresh tasks.
-				c.reloader.Close()
+				return
 			}
-			c.removeAll()
-			return
+		case <-c.drainSignal:
+			c.drainReadBuffer()
+		case <-ticker.C:
+			c.drainReadBuffer()
 		}
 	}
 }
 
+// recordAccess adds the entry to the read buffer and notifies processEntries
+// when the buffer is full. It never blocks.
+func (c *localCache) recordAccess(en *entry) {
+	if c.readBuffer.offer(en) {
+		select {
+		case c.drainSignal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// drainReadBuffer applies all accesses in the read buffers to the policy and
+// the shadow cache.
+// This function must only be called from processEntries goroutine.
+func (c *localCache) drainReadBuffer() {
+	if c.shadow != nil {
+		c.shadow.drain()
+	}
+	var n int
+	if c.loader == nil && (c.expireAfterAccess > 0 || c.expireAfterWrite > 0) {
+		// Expired entries read by GetIfPresent or Get are removed here.
+		var now time.Time
+		n = c.readBuffer.drain(func(en *entry) {
+			if now.IsZero() {
+				now = currentTime()
+			}
+			c.expireRead(en, now)
+		})
+	} else {
+		n = c.readBuffer.drain(c.access)
+	}
+	if n > 0 {
+		c.postReadCleanup(int32(n))
+	}
+}
+
 // sendEvent sends event only when the cache is not closing/closed.
 func (c *localCache) sendEvent(typ event, en *entry) {
 	if atomic.LoadInt32(&c.closing) == 0 {
@@ -253,6 +417,9 @@ func (c *localCache) write(en *entry) {
 		c.writeQueue.remove(ren)
 		// An entry has been evicted
 		c.stats.RecordEviction()
+		if c.onEviction != nil {
+			c.onEviction(ren)
+		}
 		if c.onRemoval != nil {
 			c.onRemoval(ren.key, ren.getValue())
 		}
@@ -278,6 +445,18 @@ func (c *localCache) remove(en *entry) {
 	}
 }
 
+// expireRead applies a buffered read of the entry, or removes it if it has
+// expired. Invalidated entries are left to their delete events, which are
+// ordered with writes of the same entries.
+// This function must only be called from processEntries goroutine.
+func (c *localCache) expireRead(en *entry, now time.Time) {
+	if !en.getInvalidated() && c.isExpired(en, now) {
+		c.remove(en)
+	} else {
+		c.access(en)
+	}
+}
+
 // access moves the given element to the top of the entries list.
 // This function must only be called from processEntries goroutine.
 func (c *localCache) access(en *entry) {
@@ -299,14 +478,18 @@ func (c *localCache) load(k Key) (Value, error) {
 		c.stats.RecordLoadError(loadTime)
 		return nil, err
 	}
-	en := newEntry(k, v, sum(k))
+	en := newEntry(k, v, c.hasher.sum(k))
 	c.setEntryWriteTime(en, now)
 	c.setEntryAccessTime(en, now)
+	c.setEntryWeight(en, v)
+	en.setLoadCost(int64(loadTime))
 	if c.cap == 0 || c.cache.len() < c.cap {
 		cen := c.cache.getOrSet(en)
 		if cen != nil {
 			cen.setValue(v)
 			c.setEntryWriteTime(cen, now)
+			c.setEntryWeight(cen, v)
+			cen.setLoadCost(int64(loadTime))
 			en = cen
 		}
 	}
@@ -342,6 +525,8 @@ func (c *localCache) refresh(en *entry) {
 	if err == nil {
 		en.setValue(v)
 		c.setEntryWriteTime(en, now)
+		c.setEntryWeight(en, v)
+		en.setLoadCost(int64(loadTime))
 		c.sendEvent(eventWrite, en)
 		c.stats.RecordLoadSuccess(loadTime)
 	} else {
@@ -360,6 +545,8 @@ func (c *localCache) reload(en *entry) {
 		if err == nil {
 			en.setValue(newValue)
 			c.setEntryWriteTime(en, now)
+			c.setEntryWeight(en, newValue)
+			en.setLoadCost(int64(loadTime))
 			c.sendEvent(eventWrite, en)
 			c.stats.RecordLoadSuccess(loadTime)
 		} else {
@@ -369,10 +556,10 @@ func (c *localCache) reload(en *entry) {
 	c.reloader.Reload(en.key, en.getValue(), setFn)
 }
 
-// postReadCleanup is run after entry access/delete event.
+// postReadCleanup is run after n entries are accessed or deleted.
 // This function must only be called from processEntries goroutine.
-func (c *localCache) postReadCleanup() {
-	if atomic.AddInt32(&c.readCount, 1) > drainThreshold {
+func (c *localCache) postReadCleanup(n int32) {
+	if atomic.AddInt32(&c.readCount, n) > drainThreshold {
 		atomic.StoreInt32(&c.readCount, 0)
 		c.expireEntries()
 	}
@@ -477,12 +664,50 @@ func (c *localCache) setEntryWriteTime(en *entry, now time.Time) {
 	}
 }
 
+// setEntryWeight sets weight of the entry if weigher is set.
+func (c *localCache) setEntryWeight(en *entry, v Value) {
+	if c.weigher != nil {
+		en.setWeight(int32(c.weigher(en.key, v)))
+	}
+}
+
+// recordHit records a cache hit and the load time saved by the entry.
+func (c *localCache) recordHit(en *entry) {
+	c.stats.RecordHits(1)
+	if c.savedRecorder != nil {
+		if cost := en.getLoadCost(); cost > 0 {
+			c.savedRecorder.RecordLoadTimeSaved(time.Duration(cost))
+		}
+	}
+}
+
+// recordRequest buffers the request of key hash h for the shadow cache and
+// notifies processEntries when the buffer is full. It never blocks.
+func (c *localCache) recordRequest(h uint64) {
+	if c.shadow != nil && c.shadow.offer(h) {
+		select {
+		case c.drainSignal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// recordTrace records operation op of the key hash h.
+func (c *localCache) recordTrace(op TraceOp, h uint64) {
+	if c.recorder != nil {
+		c.recorder.record(op, h)
+	}
+}
+
 // New returns a local in-memory Cache.
 func New(options ...Option) Cache {
 	c := newLocalCache()
 	for _, opt := range options {
 		opt(c)
 	}
+	if c.shards > 1 {
+		return newShardedCache(c.shards, nil, options)
+	}
 	c.init()
 	return c
 }
@@ -495,6 +720,9 @@ func NewLoadingCache(loader LoaderFunc, options ...Option) LoadingCache {
 	for _, opt := range options {
 		opt(c)
 	}
+	if c.shards > 1 {
+		return newShardedCache(c.shards, loader, options)
+	}
 	c.init()
 	return c
 }
@@ -516,6 +744,32 @@ func WithMaximumSize(size int) Option {
 	}
 }
 
+// WithConcurrencyLevel returns an Option which sets the number of segments
+// of the cache data store to 2 ^ level. Higher level reduces contention of
+// concurrent writes but uses more memory.
+func WithConcurrencyLevel(level int) Option {
+	if level < 0 {
+		level = 0
+	}
+	if level > maximumConcurrencyLevel {
+		level = maximumConcurrencyLevel
+	}
+	return func(c *localCache) {
+		c.concurrencyLevel = level
+	}
+}
+
+// WithShards returns an Option which splits the cache into n independent
+// caches, each has its own policy and maintenance goroutine, so throughput
+// scales with number of cores. The maximum size is divided among shards,
+// therefore it is only enforced approximately.
+// This option is not applicable for Tiered cache.
+func WithShards(n int) Option {
+	return func(c *localCache) {
+		c.shards = n
+	}
+}
+
 // WithRemovalListener returns an Option to set cache to call onRemoval for each
 // entry evicted from the cache.
 func WithRemovalListener(onRemoval Func) Option {
@@ -556,13 +810,24 @@ func WithStatsCounter(st StatsCounter) Option {
 }
 
 // WithPolicy returns an option which sets cache policy associated to the given name.
-// Supported policies are: lru, slru, tinylfu.
+// Supported policies are: lru, slru, tinylfu, adaptive (TinyLFU with
+// adaptive window size), arc (Adaptive Replacement Cache), lirs
+// (Low Inter-reference Recency Set), s3fifo, sieve and gdsf
+// (GreedyDual-Size-Frequency, which keeps entries expensive to load).
 func WithPolicy(name string) Option {
 	return func(c *localCache) {
 		c.policyName = name
 	}
 }
 
+// WithCustomPolicy returns an option which sets a user-defined cache policy.
+// It overrides WithPolicy and cannot be used with WithShards.
+func WithCustomPolicy(p Policy) Option {
+	return func(c *localCache) {
+		c.customPolicy = p
+	}
+}
+
 // WithReloader returns an option which sets reloader for a loading cache.
 // By default, each asynchronous reload is run in a go routine.
 // This option is only applicable for LoadingCache.
@@ -572,6 +837,71 @@ func WithReloader(reloader Reloader) Option {
 	}
 }
 
+// WithEvictionSpill returns an option which makes a Tiered cache write entries
+// evicted from its first level to the second level instead of writing through
+// to both levels on every Put.
+// This option is only applicable for Tiered cache.
+func WithEvictionSpill() Option {
+	return func(c *localCache) {
+		c.spill = true
+	}
+}
+
+// WithInvalidationBus returns an option which publishes invalidations of
+// the cache to the given bus and applies invalidations received from it.
+func WithInvalidationBus(bus InvalidationBus) Option {
+	return func(c *localCache) {
+		c.bus = bus
+	}
+}
+
+// WithHasher returns an option which sets the function calculating hash
+// values of keys. It takes precedence over the Hash interface of keys.
+// The function must return the same value for equal keys and should be
+// resistant to collisions if keys are provided by untrusted users.
+func WithHasher(fn func(Key) uint64) Option {
+	return func(c *localCache) {
+		c.hasher.custom = fn
+	}
+}
+
+// WithWeigher returns an option which sets the function computing weight of
+// cache entries. The weight is used by cost-aware policies such as gdsf,
+// where heavier entries are more likely to be evicted.
+func WithWeigher(weigher Weigher) Option {
+	return func(c *localCache) {
+		c.weigher = weigher
+	}
+}
+
+// WithHitRateEstimation returns an option which estimates hit rates of an
+// LRU cache at the given multiples of the maximum size, 0.5, 1, 2 and 4 by
+// default. Requests are sampled by key hash into small shadow lists, so
+// the overhead is bounded regardless of the cache size. Sampled requests are
+// buffered and may be dropped under contention, like accesses of entries.
+// Estimates are available via HitRateEstimator.
+func WithHitRateEstimation(multipliers ...float64) Option {
+	if len(multipliers) == 0 {
+		multipliers = defaultShadowMultipliers
+	}
+	return func(c *localCache) {
+		c.shadowMultipliers = multipliers
+	}
+}
+
+// WithTraceRecorder returns an option which writes GetIfPresent, Get, Put
+// and Invalidate operations to w in the format read by TraceReader.
+// Keys are sampled by their hashes at the given rate in (0, 1], so all
+// operations of a sampled key are recorded. Records are written in a
+// separate goroutine and dropped when it falls behind, so callers are never
+// blocked. Buffered records are flushed when the cache is closed.
+func WithTraceRecorder(w io.Writer, rate float64) Option {
+	return func(c *localCache) {
+		c.traceWriter = w
+		c.traceRate = rate
+	}
+}
+
 // withInsertionListener is used for testing.
 func withInsertionListener(onInsertion Func) Option {
 	return func(c *localCache) {
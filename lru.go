@@ -176,13 +176,24 @@ func (l *slruCache) markAccess(en *entry) {
 	if l.protectedCap > 0 && l.protectedLs.Len() > l.protectedCap {
 		// Protected list capacity exceeded, move the last entry in the protected segment to
 		// the probation segment.
-		en = getEntry(l.protectedLs.Back())
-		en.listID = probationSegment
-		l.protectedLs.Remove(en.accessList)
-		en.accessList = l.probationLs.PushFront(en)
+		l.demote()
 	}
 }
 
+// demote moves the last entry in the protected segment to the probation segment
+// and returns that entry or nil if the protected segment is empty.
+func (l *slruCache) demote() *entry {
+	el := l.protectedLs.Back()
+	if el == nil {
+		return nil
+	}
+	en := getEntry(el)
+	en.listID = probationSegment
+	l.protectedLs.Remove(en.accessList)
+	en.accessList = l.probationLs.PushFront(en)
+	return en
+}
+
 // remove removes an entry from the cache and returns the removed entry or nil
 // if it is not found.
 func (l *slruCache) remove(en *entry) *entry {
@@ -77,6 +77,7 @@ func (t *lruTest) assertSLRUEntry(k int, id uint8) {
 
 func TestLRU(t *testing.T) {
 	s := lruTest{t: t}
+	s.c.init(defaultConcurrencyLevel)
 	s.lru.init(&s.c, 3)
 
 	en := createLRUEntries(4)
@@ -124,6 +125,7 @@ func TestLRU(t *testing.T) {
 
 func TestLRUWalk(t *testing.T) {
 	s := lruTest{t: t}
+	s.c.init(defaultConcurrencyLevel)
 	s.lru.init(&s.c, 5)
 
 	entries := createLRUEntries(6)
@@ -162,6 +164,7 @@ func TestLRUWalk(t *testing.T) {
 
 func TestSegmentedLRU(t *testing.T) {
 	s := lruTest{t: t}
+	s.c.init(defaultConcurrencyLevel)
 	s.slru.init(&s.c, 3)
 	s.slru.probationCap = 1
 	s.slru.protectedCap = 2
@@ -230,6 +233,7 @@ func TestSegmentedLRU(t *testing.T) {
 
 func TestSLRUWalk(t *testing.T) {
 	s := lruTest{t: t}
+	s.c.init(defaultConcurrencyLevel)
 	s.slru.init(&s.c, 6)
 
 	entries := createLRUEntries(10)
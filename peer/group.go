@@ -0,0 +1,208 @@
+// Package peer provides a distributed loading cache. Each key is assigned an
+// owner peer by a consistent hash ring. Only the owner loads the value and
+// other peers fetch it from the owner over HTTP.
+package peer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/goburrow/cache"
+)
+
+// DefaultBasePath is the default HTTP path prefix of groups.
+const DefaultBasePath = "/_cache/"
+
+// Getter loads value for the given key.
+type Getter func(key string) ([]byte, error)
+
+// Group is a loading cache which is distributed among peers.
+// Group implements http.Handler to serve values of keys it owns.
+type Group struct {
+	name     string
+	self     string
+	basePath string
+	replicas int
+	getter   Getter
+
+	// main caches values of keys owned by this peer.
+	main cache.LoadingCache
+	// hot mirrors values of hot keys owned by other peers.
+	hot cache.Cache
+
+	client       *http.Client
+	cacheOptions []cache.Option
+	hotSize      int
+
+	mu   sync.RWMutex
+	ring *Ring
+}
+
+// NewGroup returns a new Group with name and URL of this peer.
+// SetPeers must be called to add other peers, otherwise all keys are loaded
+// locally.
+func NewGroup(name, self string, getter Getter, options ...Option) *Group {
+	g := &Group{
+		name:     name,
+		self:     self,
+		basePath: DefaultBasePath,
+		getter:   getter,
+		client:   http.DefaultClient,
+		ring:     NewRing(0),
+	}
+	for _, opt := range options {
+		opt(g)
+	}
+	g.main = cache.NewLoadingCache(g.load, g.cacheOptions...)
+	if g.hotSize > 0 {
+		g.hot = cache.New(cache.WithMaximumSize(g.hotSize), cache.WithPolicy("tinylfu"))
+	}
+	return g
+}
+
+// SetPeers sets base URLs of all peers, including this peer.
+func (g *Group) SetPeers(peers ...string) {
+	ring := NewRing(g.replicas, peers...)
+	g.mu.Lock()
+	g.ring = ring
+	g.mu.Unlock()
+}
+
+// Get returns value for the given key. If this peer does not own the key,
+// value is fetched from the owner. When the owner can not be reached, value
+// is loaded locally.
+func (g *Group) Get(key string) ([]byte, error) {
+	owner := g.owner(key)
+	if owner == "" || owner == g.self {
+		return g.get(key)
+	}
+	if g.hot != nil {
+		if v, ok := g.hot.GetIfPresent(key); ok {
+			return v.([]byte), nil
+		}
+	}
+	v, err := g.fetch(owner, key)
+	if err != nil {
+		return g.get(key)
+	}
+	if g.hot != nil {
+		g.hot.Put(key, v)
+	}
+	return v, nil
+}
+
+// Stats copies stats of the cache for keys owned by this peer to t.
+func (g *Group) Stats(t *cache.Stats) {
+	g.main.Stats(t)
+}
+
+// Close closes all caches of this group.
+func (g *Group) Close() error {
+	if g.hot != nil {
+		g.hot.Close()
+	}
+	return g.main.Close()
+}
+
+// ServeHTTP serves value of the key in request path.
+// The value is always loaded locally to avoid forwarding loops between peers
+// with different views of the ring.
+func (g *Group) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := g.basePath + g.name + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.NotFound(w, r)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+	v, err := g.get(key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(v)
+}
+
+func (g *Group) owner(key string) string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.ring.Get(key)
+}
+
+func (g *Group) get(key string) ([]byte, error) {
+	v, err := g.main.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
+
+func (g *Group) load(k cache.Key) (cache.Value, error) {
+	return g.getter(k.(string))
+}
+
+// fetch gets value of the key from the given peer.
+func (g *Group) fetch(peer, key string) ([]byte, error) {
+	u := strings.TrimSuffix(peer, "/") + g.basePath + url.PathEscape(g.name) + "/" + url.PathEscape(key)
+	res, err := g.client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("peer: %s returned %s: %s", peer, res.Status, strings.TrimSpace(string(b)))
+	}
+	return b, nil
+}
+
+// Option add options for Group.
+type Option func(g *Group)
+
+// WithBasePath returns an Option which sets HTTP path prefix of the group.
+// All peers must use the same base path.
+func WithBasePath(path string) Option {
+	return func(g *Group) {
+		g.basePath = path
+	}
+}
+
+// WithReplicas returns an Option which sets number of virtual nodes of each
+// peer in the consistent hash ring.
+func WithReplicas(n int) Option {
+	return func(g *Group) {
+		g.replicas = n
+	}
+}
+
+// WithHTTPClient returns an Option which sets HTTP client for fetching values
+// from other peers.
+func WithHTTPClient(client *http.Client) Option {
+	return func(g *Group) {
+		g.client = client
+	}
+}
+
+// WithCacheOptions returns an Option which sets options for the cache of keys
+// owned by this peer.
+func WithCacheOptions(options ...cache.Option) Option {
+	return func(g *Group) {
+		g.cacheOptions = options
+	}
+}
+
+// WithHotCache returns an Option which enables mirroring values of hot keys
+// owned by other peers in a local cache with the given size.
+// Only frequently requested keys are kept as the hot cache uses TinyLFU policy.
+func WithHotCache(size int) Option {
+	return func(g *Group) {
+		g.hotSize = size
+	}
+}
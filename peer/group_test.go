@@ -0,0 +1,124 @@
+package peer
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type testPeers struct {
+	groups   []*Group
+	servers  []*httptest.Server
+	requests int32
+
+	mu     sync.Mutex
+	loaded map[string]int
+}
+
+func newTestPeers(t *testing.T, n int, options ...Option) *testPeers {
+	p := &testPeers{
+		groups:  make([]*Group, n),
+		servers: make([]*httptest.Server, n),
+		loaded:  make(map[string]int),
+	}
+	getter := func(key string) ([]byte, error) {
+		if key == "error" {
+			return nil, errors.New("error")
+		}
+		p.mu.Lock()
+		p.loaded[key]++
+		p.mu.Unlock()
+		return []byte("value-" + key), nil
+	}
+	urls := make([]string, n)
+	for i := range p.groups {
+		i := i
+		p.servers[i] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&p.requests, 1)
+			p.groups[i].ServeHTTP(w, r)
+		}))
+		urls[i] = p.servers[i].URL
+	}
+	for i := range p.groups {
+		p.groups[i] = NewGroup("test", urls[i], getter, options...)
+		p.groups[i].SetPeers(urls...)
+	}
+	return p
+}
+
+func (p *testPeers) Close() {
+	for i := range p.groups {
+		p.servers[i].Close()
+		p.groups[i].Close()
+	}
+}
+
+func TestGroup(t *testing.T) {
+	p := newTestPeers(t, 3)
+	defer p.Close()
+
+	const n = 30
+	for _, g := range p.groups {
+		for i := 0; i < n; i++ {
+			k := strconv.Itoa(i)
+			v, err := g.Get(k)
+			if err != nil || string(v) != "value-"+k {
+				t.Fatalf("unexpected get: %s %v", v, err)
+			}
+		}
+	}
+	if len(p.loaded) != n {
+		t.Fatalf("unexpected loaded keys: %v", p.loaded)
+	}
+	for k, c := range p.loaded {
+		if c != 1 {
+			t.Fatalf("unexpected load count of %s: %d", k, c)
+		}
+	}
+	_, err := p.groups[0].Get("error")
+	if err == nil {
+		t.Fatalf("expect error")
+	}
+}
+
+func TestGroupHotCache(t *testing.T) {
+	p := newTestPeers(t, 2, WithHotCache(10))
+	defer p.Close()
+
+	g := p.groups[0]
+	var k string
+	for i := 0; ; i++ {
+		k = strconv.Itoa(i)
+		if g.owner(k) != g.self {
+			break
+		}
+	}
+	for i := 0; i < 3; i++ {
+		v, err := g.Get(k)
+		if err != nil || string(v) != "value-"+k {
+			t.Fatalf("unexpected get: %s %v", v, err)
+		}
+	}
+	if n := atomic.LoadInt32(&p.requests); n != 1 {
+		t.Fatalf("unexpected peer requests: %d", n)
+	}
+}
+
+func TestGroupPeerDown(t *testing.T) {
+	p := newTestPeers(t, 2)
+	defer p.Close()
+
+	g := p.groups[0]
+	p.servers[1].Close()
+	for i := 0; i < 10; i++ {
+		k := strconv.Itoa(i)
+		v, err := g.Get(k)
+		if err != nil || string(v) != "value-"+k {
+			t.Fatalf("unexpected get: %s %v", v, err)
+		}
+	}
+}
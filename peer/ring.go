@@ -0,0 +1,68 @@
+package peer
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+const defaultReplicas = 50
+
+// Ring is a consistent hash ring which assigns each key an owner peer.
+type Ring struct {
+	replicas int
+	hashes   []uint64 // sorted
+	peers    map[uint64]string
+}
+
+// NewRing returns a Ring with the given number of virtual nodes for each peer.
+func NewRing(replicas int, peers ...string) *Ring {
+	if replicas <= 0 {
+		replicas = defaultReplicas
+	}
+	r := &Ring{
+		replicas: replicas,
+		peers:    make(map[uint64]string, replicas*len(peers)),
+	}
+	for _, p := range peers {
+		for i := 0; i < replicas; i++ {
+			h := hashString(strconv.Itoa(i) + p)
+			r.hashes = append(r.hashes, h)
+			r.peers[h] = p
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool {
+		return r.hashes[i] < r.hashes[j]
+	})
+	return r
+}
+
+// Get returns the peer owning the given key or an empty string if the ring
+// has no peers.
+func (r *Ring) Get(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	h := hashString(key)
+	idx := sort.Search(len(r.hashes), func(i int) bool {
+		return r.hashes[i] >= h
+	})
+	if idx == len(r.hashes) {
+		idx = 0
+	}
+	return r.peers[r.hashes[idx]]
+}
+
+// hashString returns FNV-1a hash of s with bits mixed by the finalizer
+// of MurmurHash3 as FNV does not distribute short strings evenly.
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	v := h.Sum64()
+	v ^= v >> 33
+	v *= 0xff51afd7ed558ccd
+	v ^= v >> 33
+	v *= 0xc4ceb9fe1a85ec53
+	v ^= v >> 33
+	return v
+}
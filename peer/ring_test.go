@@ -0,0 +1,33 @@
+package peer
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	r := NewRing(0)
+	if p := r.Get("a"); p != "" {
+		t.Fatalf("unexpected peer: %q", p)
+	}
+	peers := []string{"a", "b", "c"}
+	r = NewRing(0, peers...)
+	owners := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		owners[r.Get(strconv.Itoa(i))]++
+	}
+	for _, p := range peers {
+		if owners[p] < 200 {
+			t.Fatalf("unexpected key distribution: %v", owners)
+		}
+	}
+	// Removing a peer should only move keys owned by that peer.
+	r2 := NewRing(0, "a", "b")
+	for i := 0; i < 1000; i++ {
+		k := strconv.Itoa(i)
+		p := r.Get(k)
+		if p != "c" && r2.Get(k) != p {
+			t.Fatalf("unexpected owner of %s: %s, want: %s", k, r2.Get(k), p)
+		}
+	}
+}
@@ -8,9 +8,8 @@ import (
 
 const (
 	// Number of cache data store will be 2 ^ concurrencyLevel.
-	concurrencyLevel = 2
-	segmentCount     = 1 << concurrencyLevel
-	segmentMask      = segmentCount - 1
+	defaultConcurrencyLevel = 2
+	maximumConcurrencyLevel = 16
 )
 
 // entry stores cached entry key and value.
@@ -24,10 +23,14 @@ type entry struct {
 	accessTime int64 // Access atomically - must be aligned on 32-bit
 	// writeTime is the last time this entry was updated.
 	writeTime int64 // Access atomically - must be aligned on 32-bit
+	// loadCost is the time spent loading value of this entry in nanoseconds.
+	loadCost int64 // Access atomically - must be aligned on 32-bit
 
 	// FIXME: More efficient way to store boolean flags
 	invalidated int32
 	loading     int32
+	// weight is the relative size of this entry value.
+	weight int32 // Access atomically
 
 	key   Key
 	value atomic.Value // Store value
@@ -40,6 +43,12 @@ type entry struct {
 	writeList *list.Element
 	// listID is ID of the list which this entry is currently in.
 	listID uint8
+	// freq is the access frequency or the visited bit of this entry in
+	// FIFO-based policies.
+	freq uint8
+	// data is the state of this entry in a custom Policy, or its node in
+	// the GDSF policy.
+	data interface{}
 }
 
 func newEntry(k Key, v Value, h uint64) *entry {
@@ -75,6 +84,22 @@ func (e *entry) setWriteTime(v int64) {
 	atomic.StoreInt64(&e.writeTime, v)
 }
 
+func (e *entry) getLoadCost() int64 {
+	return atomic.LoadInt64(&e.loadCost)
+}
+
+func (e *entry) setLoadCost(v int64) {
+	atomic.StoreInt64(&e.loadCost, v)
+}
+
+func (e *entry) getWeight() int32 {
+	return atomic.LoadInt32(&e.weight)
+}
+
+func (e *entry) setWeight(v int32) {
+	atomic.StoreInt32(&e.weight, v)
+}
+
 func (e *entry) getLoading() bool {
 	return atomic.LoadInt32(&e.loading) != 0
 }
@@ -103,12 +128,12 @@ func getEntry(el *list.Element) *entry {
 	return el.Value.(*entry)
 }
 
-// event is the cache event (add, hit or delete).
+// event is the cache event (add or delete).
+// Entry accesses are recorded in read buffers instead.
 type event uint8
 
 const (
 	eventWrite event = iota
-	eventAccess
 	eventDelete
 	eventClose
 )
@@ -120,8 +145,16 @@ type entryEvent struct {
 
 // cache is a data structure for cache entries.
 type cache struct {
-	size int64                  // Access atomically - must be aligned on 32-bit
-	segs [segmentCount]sync.Map // map[Key]*entry
+	size int64      // Access atomically - must be aligned on 32-bit
+	mask uint64     // Number of segments - 1
+	segs []sync.Map // map[Key]*entry
+}
+
+// init creates 2 ^ concurrencyLevel segments.
+func (c *cache) init(concurrencyLevel int) {
+	n := 1 << uint(concurrencyLevel)
+	c.segs = make([]sync.Map, n)
+	c.mask = uint64(n - 1)
 }
 
 func (c *cache) get(k Key, h uint64) *entry {
@@ -163,7 +196,7 @@ func (c *cache) walk(fn func(*entry)) {
 }
 
 func (c *cache) segment(h uint64) *sync.Map {
-	return &c.segs[h&segmentMask]
+	return &c.segs[h&c.mask]
 }
 
 // policy is a cache policy.
@@ -190,6 +223,18 @@ func newPolicy(name string) policy {
 		return &lruCache{}
 	case "tinylfu":
 		return &tinyLFU{}
+	case "adaptive":
+		return &adaptiveTinyLFU{}
+	case "arc":
+		return &arcCache{}
+	case "lirs":
+		return &lirsCache{}
+	case "s3fifo":
+		return &s3fifoCache{}
+	case "sieve":
+		return &sieveCache{}
+	case "gdsf":
+		return &gdsfCache{}
 	default:
 		panic("cache: unsupported policy " + name)
 	}
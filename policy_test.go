@@ -7,6 +7,7 @@ import (
 
 func BenchmarkCacheSegment(b *testing.B) {
 	c := cache{}
+	c.init(defaultConcurrencyLevel)
 	const count = 1 << 10
 	entries := make([]*entry, count)
 	for i := range entries {
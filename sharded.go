@@ -0,0 +1,136 @@
+package cache
+
+// shardedCache splits entries into independent local caches by key hash.
+type shardedCache struct {
+	shards []*localCache
+	stats  StatsCounter
+	hasher hasher
+	// recorder is shared by all shards when trace recording is enabled.
+	recorder *traceRecorder
+}
+
+// newShardedCache returns a cache of n shards, each is configured with the
+// given options. The maximum size is divided among shards and stats counter
+// is shared by all shards.
+func newShardedCache(n int, loader LoaderFunc, options []Option) *shardedCache {
+	s := &shardedCache{
+		shards: make([]*localCache, n),
+	}
+	s.hasher.init()
+	var busID string
+	for i := range s.shards {
+		c := newLocalCache()
+		c.loader = loader
+		for _, opt := range options {
+			opt(c)
+		}
+		if c.customPolicy != nil {
+			// A policy instance cannot be shared by shards.
+			panic("cache: custom policy cannot be used with shards")
+		}
+		if i == 0 {
+			s.stats = c.stats
+			s.hasher.custom = c.hasher.custom
+			if c.traceWriter != nil {
+				s.recorder = newTraceRecorder(c.traceWriter, c.traceRate)
+				s.recorder.start()
+			}
+			if c.bus != nil {
+				// Shards share the same identity so they do not apply
+				// invalidations published by each other.
+				busID = newBusID()
+			}
+		} else {
+			c.stats = s.stats
+		}
+		c.busID = busID
+		// Shards record traces to the recorder of this cache.
+		c.traceWriter = nil
+		c.recorder = s.recorder
+		if c.cap > 0 {
+			c.cap = (c.cap + n - 1) / n
+		}
+		c.shards = 0
+		c.init()
+		s.shards[i] = c
+	}
+	return s
+}
+
+// shard returns the shard of the given key.
+func (s *shardedCache) shard(k Key) *localCache {
+	return s.shards[spread(s.hasher.sum(k))%uint64(len(s.shards))]
+}
+
+// GetIfPresent gets cached value from the shard of k.
+func (s *shardedCache) GetIfPresent(k Key) (Value, bool) {
+	return s.shard(k).GetIfPresent(k)
+}
+
+// Put adds new entry to the shard of k.
+func (s *shardedCache) Put(k Key, v Value) {
+	s.shard(k).Put(k, v)
+}
+
+// Invalidate removes the entry associated with k.
+func (s *shardedCache) Invalidate(k Key) {
+	s.shard(k).Invalidate(k)
+}
+
+// InvalidateAll removes all entries in all shards.
+func (s *shardedCache) InvalidateAll() {
+	for _, c := range s.shards {
+		c.invalidateAll()
+	}
+	if c := s.shards[0]; c.bus != nil {
+		c.bus.Publish(Invalidation{Origin: c.busID, All: true})
+	}
+}
+
+// Get returns value associated with k from its shard.
+func (s *shardedCache) Get(k Key) (Value, error) {
+	return s.shard(k).Get(k)
+}
+
+// Refresh reloads value for k in its shard.
+func (s *shardedCache) Refresh(k Key) {
+	s.shard(k).Refresh(k)
+}
+
+// Stats copies cache stats of all shards to t.
+func (s *shardedCache) Stats(t *Stats) {
+	s.stats.Snapshot(t)
+}
+
+// EstimateHitRates implements HitRateEstimator by aggregating estimates of
+// all shards.
+func (s *shardedCache) EstimateHitRates() []HitRateEstimate {
+	var total []HitRateEstimate
+	for _, c := range s.shards {
+		e := c.EstimateHitRates()
+		if e == nil {
+			return nil
+		}
+		if total == nil {
+			total = e
+			continue
+		}
+		for i := range total {
+			total[i].Size += e[i].Size
+			total[i].Requests += e[i].Requests
+			total[i].Hits += e[i].Hits
+		}
+	}
+	return total
+}
+
+// Close closes all shards.
+func (s *shardedCache) Close() error {
+	for _, c := range s.shards {
+		c.Close()
+	}
+	if s.recorder != nil {
+		s.recorder.close()
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestConcurrencyLevel(t *testing.T) {
+	c := New(WithConcurrencyLevel(4)).(*localCache)
+	defer c.Close()
+	if len(c.cache.segs) != 16 {
+		t.Fatalf("unexpected segments: %d", len(c.cache.segs))
+	}
+	c.Put(1, 1)
+	v, ok := c.GetIfPresent(1)
+	if !ok || v.(int) != 1 {
+		t.Fatalf("unexpected value: %v (%v)", v, ok)
+	}
+}
+
+func TestShardedCache(t *testing.T) {
+	const n = 4
+	const max = 100
+	wg := sync.WaitGroup{}
+	insFunc := func(Key, Value) {
+		wg.Done()
+	}
+	c := New(WithShards(n), WithMaximumSize(max), withInsertionListener(insFunc)).(*shardedCache)
+	defer c.Close()
+	if len(c.shards) != n {
+		t.Fatalf("unexpected shards: %d", len(c.shards))
+	}
+
+	wg.Add(10 * max)
+	for i := 0; i < 10*max; i++ {
+		c.Put(i, i)
+	}
+	wg.Wait()
+	size := 0
+	for _, s := range c.shards {
+		if s.cap != max/n {
+			t.Fatalf("unexpected shard capacity: %d", s.cap)
+		}
+		size += cacheSize(&s.cache)
+	}
+	if size != max {
+		t.Fatalf("unexpected cache size: %d, want: %d", size, max)
+	}
+	hits := 0
+	for i := 0; i < 10*max; i++ {
+		if v, ok := c.GetIfPresent(i); ok {
+			if v.(int) != i {
+				t.Fatalf("unexpected value: %v, want: %v", v, i)
+			}
+			hits++
+		}
+	}
+	var st Stats
+	c.Stats(&st)
+	if st.HitCount != uint64(hits) || st.RequestCount() != 10*max {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestShardedCacheHasher(t *testing.T) {
+	// Hashes of only 32 bits are still distributed among shards and buffers.
+	hasher := func(k Key) uint64 {
+		return uint64(k.(int))
+	}
+	c := New(WithShards(4), WithHasher(hasher)).(*shardedCache)
+	defer c.Close()
+	for i := 0; i < 100; i++ {
+		c.Put(i, i)
+	}
+	for i, s := range c.shards {
+		if s.hasher.custom == nil {
+			t.Fatalf("unexpected hasher of shard %d", i)
+		}
+		if n := cacheSize(&s.cache); n == 0 {
+			t.Fatalf("unexpected empty shard %d", i)
+		}
+	}
+	b := stripedHashBuffer{
+		buffers: make([]hashBuffer, 4),
+		mask:    3,
+	}
+	for h := uint64(1); h <= 100; h++ {
+		b.offer(h)
+	}
+	for i := range b.buffers {
+		if b.buffers[i].tail == 0 {
+			t.Fatalf("unexpected empty buffer %d", i)
+		}
+	}
+}
+
+func TestShardedLoadingCache(t *testing.T) {
+	c := NewLoadingCache(simpleLoader, WithShards(2))
+	defer c.Close()
+	for i := 0; i < 10; i++ {
+		v, err := c.Get(i)
+		if err != nil || v.(int) != i {
+			t.Fatalf("unexpected get: %v %v", v, err)
+		}
+	}
+	var st Stats
+	c.Stats(&st)
+	if st.LoadSuccessCount != 10 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
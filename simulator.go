@@ -0,0 +1,80 @@
+package cache
+
+// Simulator replays requests through a cache policy synchronously in the
+// calling goroutine, so results are exactly reproducible. Keys are hashed
+// deterministically. It is intended for evaluating policies, and is not safe
+// for concurrent use.
+// Only options for maximum size, policy, hashing and weigher are applicable.
+type Simulator struct {
+	cache   cache
+	policy  policy
+	hasher  hasher
+	weigher Weigher
+	stats   Stats
+}
+
+// NewSimulator returns a Simulator with the given cache options.
+func NewSimulator(options ...Option) *Simulator {
+	c := newLocalCache()
+	for _, opt := range options {
+		opt(c)
+	}
+	s := &Simulator{}
+	s.cache.init(c.concurrencyLevel)
+	s.hasher.custom = c.hasher.custom
+	s.weigher = c.weigher
+	if c.customPolicy != nil {
+		s.policy = &customPolicy{policy: c.customPolicy}
+	} else {
+		s.policy = newPolicy(c.policyName)
+	}
+	s.policy.init(&s.cache, c.cap)
+	return s
+}
+
+// Get returns value associated with k and records the access if it is present.
+func (s *Simulator) Get(k Key) (Value, bool) {
+	en := s.cache.get(k, s.hasher.sum(k))
+	if en == nil {
+		s.stats.MissCount++
+		return nil, false
+	}
+	s.policy.access(en)
+	s.stats.HitCount++
+	return en.getValue(), true
+}
+
+// Put adds or updates the entry for k and evicts an entry if needed.
+func (s *Simulator) Put(k Key, v Value) {
+	h := s.hasher.sum(k)
+	en := s.cache.get(k, h)
+	if en == nil {
+		en = newEntry(k, v, h)
+	} else {
+		en.setValue(v)
+	}
+	if s.weigher != nil {
+		en.setWeight(int32(s.weigher(k, v)))
+	}
+	if ren := s.policy.write(en); ren != nil {
+		s.stats.EvictionCount++
+	}
+}
+
+// Invalidate removes the entry associated with k.
+func (s *Simulator) Invalidate(k Key) {
+	en := s.cache.get(k, s.hasher.sum(k))
+	if en != nil {
+		s.policy.remove(en)
+	}
+}
+
+// Len returns number of entries in the cache.
+func (s *Simulator) Len() int {
+	return s.cache.len()
+}
+
+// Stats copies simulation stats to t.
+func (s *Simulator) Stats(t *Stats) {
+	*t = s.stats
+}
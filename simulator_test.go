@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSimulator(t *testing.T) {
+	s := NewSimulator(WithMaximumSize(3))
+	for i := 0; i < 5; i++ {
+		if _, ok := s.Get(i); ok {
+			t.Fatalf("unexpected hit: %d", i)
+		}
+		s.Put(i, i)
+	}
+	if s.Len() != 3 {
+		t.Fatalf("unexpected length: %d", s.Len())
+	}
+	// Oldest entries are evicted by LRU.
+	for i := 0; i < 2; i++ {
+		if _, ok := s.Get(i); ok {
+			t.Fatalf("unexpected hit: %d", i)
+		}
+	}
+	v, ok := s.Get(4)
+	if !ok || v != 4 {
+		t.Fatalf("unexpected get: %v %v", v, ok)
+	}
+	s.Put(4, 5)
+	v, ok = s.Get(4)
+	if !ok || v != 5 {
+		t.Fatalf("unexpected get: %v %v", v, ok)
+	}
+	s.Invalidate(4)
+	if _, ok = s.Get(4); ok {
+		t.Fatal("unexpected hit after invalidate")
+	}
+	var st Stats
+	s.Stats(&st)
+	if st.HitCount != 2 || st.MissCount != 8 || st.EvictionCount != 2 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestSimulatorWeigher(t *testing.T) {
+	s := NewSimulator(WithWeigher(func(k Key, v Value) int {
+		return v.(int)
+	}))
+	s.Put(1, 10)
+	s.Put(1, 20)
+	if w := s.cache.get(1, sum(1)).getWeight(); w != 20 {
+		t.Fatalf("unexpected weight: %d", w)
+	}
+}
+
+func TestSimulatorDeterministic(t *testing.T) {
+	keys := make([]int, 10000)
+	r := rand.New(rand.NewSource(1))
+	for i := range keys {
+		keys[i] = r.Intn(1000)
+	}
+	run := func(policy string) Stats {
+		s := NewSimulator(WithMaximumSize(100), WithPolicy(policy))
+		for _, k := range keys {
+			if _, ok := s.Get(k); !ok {
+				s.Put(k, k)
+			}
+		}
+		var st Stats
+		s.Stats(&st)
+		return st
+	}
+	for _, policy := range []string{"lru", "slru", "tinylfu", "arc", "lirs", "s3fifo", "sieve"} {
+		st := run(policy)
+		if st.RequestCount() != uint64(len(keys)) {
+			t.Fatalf("%s: unexpected stats: %+v", policy, st)
+		}
+		if st != run(policy) {
+			t.Fatalf("%s: results are not reproducible", policy)
+		}
+	}
+}
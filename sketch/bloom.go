@@ -0,0 +1,139 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// BloomFilter is a Bloom filter of hashed items. It is not safe for
+// concurrent use.
+// See http://billmill.org/bloomfilter-tutorial/
+type BloomFilter struct {
+	numHashes uint32   // number of hashes per element
+	bitsMask  uint32   // size of bit vector
+	bits      []uint64 // filter bit vector
+}
+
+const (
+	// Range of false positive probability of Bloom filters.
+	minBloomFPP = 1e-9
+	maxBloomFPP = 0.5
+	// Maximum number of bits of Bloom filters, which are indexed by uint32.
+	maxBloomBits = 1 << 31
+	// Maximum number of hashes, which is above the optimal number for
+	// minBloomFPP.
+	maxBloomHashes = 64
+)
+
+// NewBloomFilter returns a BloomFilter for the given expected insertions ins
+// and false positive probability fpp. Values of fpp out of range are set to
+// the nearest supported value, and the filter size is capped at 2^31 bits.
+func NewBloomFilter(ins int, fpp float64) *BloomFilter {
+	if ins < 0 {
+		ins = 0
+	}
+	if !(fpp >= minBloomFPP) {
+		// Also for NaN.
+		fpp = minBloomFPP
+	}
+	if fpp > maxBloomFPP {
+		fpp = maxBloomFPP
+	}
+	ln2 := math.Log(2.0)
+	factor := -math.Log(fpp) / (ln2 * ln2)
+
+	bits := float64(ins) * factor
+	if bits > maxBloomBits {
+		bits = maxBloomBits
+	}
+	numBits := nextPowerOfTwo(uint32(bits))
+	if numBits == 0 {
+		numBits = 1
+	}
+	f := &BloomFilter{
+		bitsMask:  numBits - 1,
+		numHashes: 1,
+	}
+	if ins > 0 {
+		if k := uint32(ln2 * float64(numBits) / float64(ins)); k > maxBloomHashes {
+			f.numHashes = maxBloomHashes
+		} else if k > 1 {
+			f.numHashes = k
+		}
+	}
+	f.bits = make([]uint64, (numBits+63)/64)
+	return f
+}
+
+// Put inserts a hash value into the bloom filter.
+// It returns true if the value may already in the filter.
+func (f *BloomFilter) Put(h uint64) bool {
+	h1, h2 := uint32(h), uint32(h>>32)
+	var o uint = 1
+	for i := uint32(0); i < f.numHashes; i++ {
+		o &= f.set((h1 + (i * h2)) & f.bitsMask)
+	}
+	return o == 1
+}
+
+// Contains returns true if the given hash is may be in the filter.
+func (f *BloomFilter) Contains(h uint64) bool {
+	h1, h2 := uint32(h), uint32(h>>32)
+	var o uint = 1
+	for i := uint32(0); i < f.numHashes; i++ {
+		o &= f.get((h1 + (i * h2)) & f.bitsMask)
+	}
+	return o == 1
+}
+
+// set sets bit at index i and returns previous value.
+func (f *BloomFilter) set(i uint32) uint {
+	idx, shift := i/64, i%64
+	val := f.bits[idx]
+	mask := uint64(1) << shift
+	f.bits[idx] |= mask
+	return uint((val & mask) >> shift)
+}
+
+// get returns bit set at index i.
+func (f *BloomFilter) get(i uint32) uint {
+	idx, shift := i/64, i%64
+	val := f.bits[idx]
+	mask := uint64(1) << shift
+	return uint((val & mask) >> shift)
+}
+
+// Reset clears the bloom filter.
+func (f *BloomFilter) Reset() {
+	for i := range f.bits {
+		f.bits[i] = 0
+	}
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (f *BloomFilter) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 8+8*len(f.bits))
+	binary.BigEndian.PutUint32(b, f.numHashes)
+	binary.BigEndian.PutUint32(b[4:], f.bitsMask)
+	putWords(b[8:], f.bits)
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (f *BloomFilter) UnmarshalBinary(b []byte) error {
+	if len(b) < 8 {
+		return errInvalidData
+	}
+	numHashes := binary.BigEndian.Uint32(b)
+	bitsMask := binary.BigEndian.Uint32(b[4:])
+	size := (uint64(bitsMask) + 64) / 64
+	if numHashes < 1 || numHashes > maxBloomHashes ||
+		bitsMask&(bitsMask+1) != 0 || uint64(len(b)-8) != 8*size {
+		return errInvalidData
+	}
+	f.numHashes = numHashes
+	f.bitsMask = bitsMask
+	f.bits = make([]uint64, size)
+	getWords(f.bits, b[8:])
+	return nil
+}
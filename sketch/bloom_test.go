@@ -0,0 +1,97 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestBloomFilter(t *testing.T) {
+	const numIns = 100000
+	f := NewBloomFilter(numIns, 0.01)
+
+	var i uint64
+	for i = 0; i < numIns; i += 2 {
+		existed := f.Put(i)
+		if existed {
+			t.Fatalf("unexpected put(%d): %v, want: false", i, existed)
+		}
+	}
+	for i = 0; i < numIns; i += 2 {
+		existed := f.Contains(i)
+		if !existed {
+			t.Fatalf("unexpected contains(%d): %v, want: true", i, existed)
+		}
+	}
+	for i = 1; i < numIns; i += 2 {
+		existed := f.Contains(i)
+		if existed {
+			t.Fatalf("unexpected contains(%d): %v, want: false", i, existed)
+		}
+	}
+	for i = 0; i < numIns; i += 2 {
+		existed := f.Put(i)
+		if !existed {
+			t.Fatalf("unexpected put(%d): %v, want: true", i, existed)
+		}
+	}
+	f.Reset()
+	if f.Contains(0) {
+		t.Fatalf("unexpected contains(%d): %v, want: false", 0, true)
+	}
+}
+
+func TestBloomFilterParameters(t *testing.T) {
+	tests := []struct {
+		ins int
+		fpp float64
+	}{
+		{100, 0},
+		{100, -1},
+		{100, 1},
+		{100, 2},
+		{100, math.NaN()},
+		{-1, 0.01},
+	}
+	for _, tt := range tests {
+		f := NewBloomFilter(tt.ins, tt.fpp)
+		if f.numHashes < 1 || f.bitsMask&(f.bitsMask+1) != 0 ||
+			uint64(len(f.bits)) != (uint64(f.bitsMask)+64)/64 {
+			t.Fatalf("unexpected filter of %d, %v: hashes=%d mask=%x words=%d",
+				tt.ins, tt.fpp, f.numHashes, f.bitsMask, len(f.bits))
+		}
+		f.Put(1)
+		if !f.Contains(1) {
+			t.Fatalf("unexpected contains of %d, %v: false", tt.ins, tt.fpp)
+		}
+	}
+}
+
+func TestBloomFilterMarshal(t *testing.T) {
+	f := NewBloomFilter(1000, 0.01)
+	for i := uint64(0); i < 100; i++ {
+		f.Put(i)
+	}
+	b, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f2 BloomFilter
+	if err = f2.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(0); i < 200; i++ {
+		if f.Contains(i) != f2.Contains(i) {
+			t.Fatalf("unexpected contains(%d): %v", i, f2.Contains(i))
+		}
+	}
+	if err = f2.UnmarshalBinary(b[:4]); err == nil {
+		t.Fatal("expected error")
+	}
+	for _, n := range []uint32{0, maxBloomHashes + 1} {
+		binary.BigEndian.PutUint32(b, n)
+		if err = f2.UnmarshalBinary(b); err == nil {
+			t.Fatalf("expected error of %d hashes", n)
+		}
+	}
+}
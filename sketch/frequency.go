@@ -0,0 +1,172 @@
+// Package sketch provides compact probabilistic data structures for
+// estimating frequency and membership of hashed items.
+package sketch
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// DefaultDepth is the default number of rows of a FrequencySketch.
+const DefaultDepth = 4
+
+const (
+	// Maximum value of a 4-bit counter.
+	maxCount = 15
+	// Number of 4-bit counters in a word.
+	countersPerWord = 16
+	resetMask       = 0x7777777777777777
+	// Maximum number of counters in a row, which are indexed by uint32.
+	maxSketchWidth = 1 << 31
+)
+
+var errInvalidData = errors.New("sketch: invalid data")
+
+// FrequencySketch is a count-min sketch with 4-bit counters, so estimated
+// frequencies are at most 15. It is not safe for concurrent use.
+// See http://dimacs.rutgers.edu/~graham/pubs/papers/cmsoft.pdf
+type FrequencySketch struct {
+	depth    uint32
+	mask     uint32 // Number of counters in a row - 1
+	rowWords uint32 // Number of words in a row
+	counters []uint64
+}
+
+// NewFrequencySketch returns a FrequencySketch with depth rows, each has
+// width counters. The width is rounded up to a power of two and capped at
+// 2^31. Non-positive width or depth is set to the minimum value.
+func NewFrequencySketch(width, depth int) *FrequencySketch {
+	if depth < 1 {
+		depth = 1
+	}
+	c := &FrequencySketch{}
+	c.init(sketchWidth(width), uint32(depth))
+	return c
+}
+
+// sketchWidth returns the number of counters in a row for the given width.
+func sketchWidth(width int) uint32 {
+	if width < countersPerWord {
+		return countersPerWord
+	}
+	if uint64(width) > maxSketchWidth {
+		return maxSketchWidth
+	}
+	return nextPowerOfTwo(uint32(width))
+}
+
+func (c *FrequencySketch) init(width, depth uint32) {
+	c.depth = depth
+	c.mask = width - 1
+	c.rowWords = width / countersPerWord
+	c.counters = make([]uint64, c.rowWords*depth)
+}
+
+// Width returns number of counters in each row.
+func (c *FrequencySketch) Width() int {
+	return int(c.mask + 1)
+}
+
+// Depth returns number of rows.
+func (c *FrequencySketch) Depth() int {
+	return int(c.depth)
+}
+
+// Add increases counters associated with the given hash.
+func (c *FrequencySketch) Add(h uint64) {
+	h1, h2 := uint32(h), uint32(h>>32)
+	for i := uint32(0); i < c.depth; i++ {
+		idx, off := c.position(i, h1+i*h2)
+		v := c.counters[idx]
+		if uint8(v>>off)&0x0F < maxCount {
+			c.counters[idx] = v + (1 << off)
+		}
+	}
+}
+
+// Estimate returns minimum value of counters associated with the given hash.
+func (c *FrequencySketch) Estimate(h uint64) uint8 {
+	h1, h2 := uint32(h), uint32(h>>32)
+	var min uint8 = 0xFF
+	for i := uint32(0); i < c.depth; i++ {
+		idx, off := c.position(i, h1+i*h2)
+		count := uint8(c.counters[idx]>>off) & 0x0F
+		if count < min {
+			min = count
+		}
+	}
+	return min
+}
+
+// Reset divides all counters by two, so old frequencies are aged.
+func (c *FrequencySketch) Reset() {
+	for i, v := range c.counters {
+		if v != 0 {
+			c.counters[i] = (v >> 1) & resetMask
+		}
+	}
+}
+
+// Clear sets all counters to zero.
+func (c *FrequencySketch) Clear() {
+	for i := range c.counters {
+		c.counters[i] = 0
+	}
+}
+
+// position returns word index and bit offset of the counter in the given row.
+func (c *FrequencySketch) position(row, h uint32) (idx uint32, off uint32) {
+	h &= c.mask
+	idx = row*c.rowWords + h/countersPerWord
+	off = (h % countersPerWord) << 2
+	return
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (c *FrequencySketch) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 8+8*len(c.counters))
+	binary.BigEndian.PutUint32(b, c.mask+1)
+	binary.BigEndian.PutUint32(b[4:], c.depth)
+	putWords(b[8:], c.counters)
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (c *FrequencySketch) UnmarshalBinary(b []byte) error {
+	if len(b) < 8 {
+		return errInvalidData
+	}
+	width := binary.BigEndian.Uint32(b)
+	depth := binary.BigEndian.Uint32(b[4:])
+	if width < countersPerWord || width&(width-1) != 0 || depth < 1 ||
+		uint64(len(b)-8) != 8*uint64(width/countersPerWord)*uint64(depth) {
+		return errInvalidData
+	}
+	c.init(width, depth)
+	getWords(c.counters, b[8:])
+	return nil
+}
+
+func putWords(b []byte, words []uint64) {
+	for i, v := range words {
+		binary.BigEndian.PutUint64(b[8*i:], v)
+	}
+}
+
+func getWords(words []uint64, b []byte) {
+	for i := range words {
+		words[i] = binary.BigEndian.Uint64(b[8*i:])
+	}
+}
+
+// nextPowerOfTwo returns the smallest power of two which is greater than or equal to i.
+func nextPowerOfTwo(i uint32) uint32 {
+	n := i - 1
+	n |= n >> 1
+	n |= n >> 2
+	n |= n >> 4
+	n |= n >> 8
+	n |= n >> 16
+	n++
+	return n
+}
@@ -0,0 +1,100 @@
+package sketch
+
+import "testing"
+
+func TestFrequencySketch(t *testing.T) {
+	const max = 15
+	cm := NewFrequencySketch(max, DefaultDepth)
+	for i := 0; i < max; i++ {
+		// Increase value at i j times
+		for j := i; j > 0; j-- {
+			cm.Add(uint64(i))
+		}
+	}
+	for i := 0; i < max; i++ {
+		n := cm.Estimate(uint64(i))
+		if int(n) != i {
+			t.Fatalf("unexpected estimate(%d): %d, want: %d", i, n, i)
+		}
+	}
+	cm.Reset()
+	for i := 0; i < max; i++ {
+		n := cm.Estimate(uint64(i))
+		if int(n) != i/2 {
+			t.Fatalf("unexpected estimate(%d): %d, want: %d", i, n, i/2)
+		}
+	}
+	cm.Reset()
+	for i := 0; i < max; i++ {
+		n := cm.Estimate(uint64(i))
+		if int(n) != i/4 {
+			t.Fatalf("unexpected estimate(%d): %d, want: %d", i, n, i/4)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		cm.Add(1)
+	}
+	n := cm.Estimate(1)
+	if n != 15 {
+		t.Fatalf("unexpected estimate(%d): %d, want: %d", 1, n, 15)
+	}
+	cm.Clear()
+	if n = cm.Estimate(1); n != 0 {
+		t.Fatalf("unexpected estimate(%d): %d, want: %d", 1, n, 0)
+	}
+}
+
+func TestFrequencySketchSize(t *testing.T) {
+	cm := NewFrequencySketch(100, 8)
+	if cm.Width() != 128 || cm.Depth() != 8 {
+		t.Fatalf("unexpected size: %d x %d", cm.Width(), cm.Depth())
+	}
+	cm = NewFrequencySketch(0, 0)
+	if cm.Width() != 16 || cm.Depth() != 1 {
+		t.Fatalf("unexpected size: %d x %d", cm.Width(), cm.Depth())
+	}
+	// Widths above 2^31 would overflow to 0 when rounded up.
+	maxInt := int(^uint(0) >> 1)
+	for _, w := range []int{3 << 29, maxInt} {
+		if n := sketchWidth(w); n != maxSketchWidth {
+			t.Fatalf("unexpected width of %d: %d", w, n)
+		}
+	}
+}
+
+func TestFrequencySketchMarshal(t *testing.T) {
+	cm := NewFrequencySketch(64, 3)
+	for i := 0; i < 10; i++ {
+		cm.Add(uint64(i) * 0x9E3779B97F4A7C15)
+	}
+	b, err := cm.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cm2 FrequencySketch
+	if err = cm2.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if cm2.Width() != 64 || cm2.Depth() != 3 {
+		t.Fatalf("unexpected size: %d x %d", cm2.Width(), cm2.Depth())
+	}
+	for i := 0; i < 10; i++ {
+		h := uint64(i) * 0x9E3779B97F4A7C15
+		if cm.Estimate(h) != cm2.Estimate(h) {
+			t.Fatalf("unexpected estimate(%d): %d, want: %d", h, cm2.Estimate(h), cm.Estimate(h))
+		}
+	}
+	if err = cm2.UnmarshalBinary(b[:len(b)-1]); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func BenchmarkFrequencySketchReset(b *testing.B) {
+	cm := NewFrequencySketch(1<<15-1, DefaultDepth)
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		cm.Add(0xCAFECAFECAFECAFE)
+		cm.Reset()
+	}
+}
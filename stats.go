@@ -14,6 +14,10 @@ type Stats struct {
 	LoadErrorCount   uint64
 	TotalLoadTime    time.Duration
 	EvictionCount    uint64
+	// LoadTimeSaved is the total load time of values returned by cache hits.
+	// It is only recorded when the StatsCounter implements
+	// RecordLoadTimeSaved(time.Duration).
+	LoadTimeSaved time.Duration
 }
 
 // RequestCount returns a total of HitCount and MissCount.
@@ -84,6 +88,12 @@ type StatsCounter interface {
 	Snapshot(*Stats)
 }
 
+// loadTimeSavedRecorder is an optional interface of StatsCounter.
+type loadTimeSavedRecorder interface {
+	// RecordLoadTimeSaved records load time of an entry returned by a cache hit.
+	RecordLoadTimeSaved(loadTime time.Duration)
+}
+
 // statsCounter is a simple implementation of StatsCounter.
 type statsCounter struct {
 	Stats
@@ -116,6 +126,11 @@ func (s *statsCounter) RecordEviction() {
 	atomic.AddUint64(&s.Stats.EvictionCount, 1)
 }
 
+// RecordLoadTimeSaved increases LoadTimeSaved atomically.
+func (s *statsCounter) RecordLoadTimeSaved(loadTime time.Duration) {
+	atomic.AddInt64((*int64)(&s.Stats.LoadTimeSaved), int64(loadTime))
+}
+
 // Snapshot copies current stats to t.
 func (s *statsCounter) Snapshot(t *Stats) {
 	t.HitCount = atomic.LoadUint64(&s.HitCount)
@@ -124,4 +139,5 @@ func (s *statsCounter) Snapshot(t *Stats) {
 	t.LoadErrorCount = atomic.LoadUint64(&s.LoadErrorCount)
 	t.TotalLoadTime = time.Duration(atomic.LoadInt64((*int64)(&s.TotalLoadTime)))
 	t.EvictionCount = atomic.LoadUint64(&s.EvictionCount)
+	t.LoadTimeSaved = time.Duration(atomic.LoadInt64((*int64)(&s.LoadTimeSaved)))
 }
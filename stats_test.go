@@ -12,6 +12,7 @@ func TestStatsCounter(t *testing.T) {
 	c.RecordLoadSuccess(2 * time.Second)
 	c.RecordLoadError(1 * time.Second)
 	c.RecordEviction()
+	c.RecordLoadTimeSaved(4 * time.Second)
 
 	var st Stats
 	c.Snapshot(&st)
@@ -34,6 +35,9 @@ func TestStatsCounter(t *testing.T) {
 	if st.EvictionCount != 1 {
 		t.Fatalf("unexpected eviction count: %v", st)
 	}
+	if st.LoadTimeSaved != 4*time.Second {
+		t.Fatalf("unexpected load time saved: %v", st)
+	}
 
 	if st.RequestCount() != 5 {
 		t.Fatalf("unexpected request count: %v", st.RequestCount())
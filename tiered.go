@@ -0,0 +1,255 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNotFound is returned by Get of a Tiered cache without a loader function
+// when the key is not present in both levels.
+var ErrNotFound = errors.New("cache: key not found")
+
+// Maximum number of entries evicted from L1 waiting to be written to L2.
+const spillQueueSize = 1024
+
+// spilledEntry is an entry evicted from L1 and its value when evicted.
+type spilledEntry struct {
+	en    *entry
+	value Value
+}
+
+// Tiered is a two-level cache which composes a local in-memory cache (L1)
+// with a user-supplied Cache (L2), usually a larger or shared store.
+//
+// Misses in L1 are looked up in L2 before calling the loader function.
+// By default, values are written through to both levels. With
+// WithEvictionSpill option, values are only written to L1 and entries
+// evicted from L1 are written to L2 asynchronously.
+type Tiered struct {
+	l1 *localCache
+	l2 Cache
+	// l2Stats records lookups in L2 made by this cache.
+	l2Stats statsCounter
+
+	loader LoaderFunc
+
+	// spills is the bounded queue of entries evicted from L1, which are
+	// written to L2 by a separate goroutine so that a slow L2 does not block
+	// L1 maintenance. Entries are dropped when it is full.
+	spills  chan spilledEntry
+	spillWG sync.WaitGroup
+	// pending is the number of queued entries of each key, and stale is the
+	// number of them which were invalidated and must not be written to L2.
+	pendingMu sync.Mutex
+	pending   map[Key]int
+	stale     map[Key]int
+	// spillMu orders writing spilled entries and invalidating L2.
+	spillMu   sync.Mutex
+	closeOnce sync.Once
+}
+
+// NewTiered returns a Tiered cache which uses l2 as its second level.
+// The options are applied to the first level cache.
+func NewTiered(l2 Cache, options ...Option) *Tiered {
+	c := newLocalCache()
+	for _, opt := range options {
+		opt(c)
+	}
+	t := &Tiered{
+		l1: c,
+		l2: l2,
+	}
+	t.init()
+	return t
+}
+
+// NewTieredLoadingCache returns a Tiered cache with given loader function.
+// Values loaded into L1 are looked up in L2 first and the loader function
+// is only called when the value is not present in both levels.
+func NewTieredLoadingCache(l2 Cache, loader LoaderFunc, options ...Option) *Tiered {
+	c := newLocalCache()
+	for _, opt := range options {
+		opt(c)
+	}
+	t := &Tiered{
+		l1:     c,
+		l2:     l2,
+		loader: loader,
+	}
+	c.loader = t.load
+	t.init()
+	return t
+}
+
+func (t *Tiered) init() {
+	if t.l1.spill {
+		t.spills = make(chan spilledEntry, spillQueueSize)
+		t.pending = make(map[Key]int)
+		t.stale = make(map[Key]int)
+		t.l1.onEviction = t.spill
+		t.spillWG.Add(1)
+		go t.processSpills()
+	}
+	t.l1.init()
+}
+
+// spill queues the entry evicted from L1 without blocking.
+func (t *Tiered) spill(en *entry) {
+	t.pendingMu.Lock()
+	select {
+	case t.spills <- spilledEntry{en, en.getValue()}:
+		t.pending[en.key]++
+	default:
+	}
+	t.pendingMu.Unlock()
+}
+
+// processSpills writes entries evicted from L1 to L2 until the queue is closed.
+func (t *Tiered) processSpills() {
+	defer t.spillWG.Done()
+	for e := range t.spills {
+		k := e.en.key
+		t.spillMu.Lock()
+		t.pendingMu.Lock()
+		stale := t.stale[k] > 0
+		if stale {
+			if t.stale[k]--; t.stale[k] == 0 {
+				delete(t.stale, k)
+			}
+		}
+		if t.pending[k]--; t.pending[k] == 0 {
+			delete(t.pending, k)
+		}
+		t.pendingMu.Unlock()
+		// Invalidated entries can still be evicted before they are removed.
+		if !stale && !e.en.getInvalidated() {
+			t.l2.Put(k, e.value)
+		}
+		t.spillMu.Unlock()
+	}
+}
+
+// GetIfPresent returns value associated with k from L1, or from L2 if it is
+// not in L1. Values found in L2 are also added to L1.
+func (t *Tiered) GetIfPresent(k Key) (Value, bool) {
+	v, ok := t.l1.GetIfPresent(k)
+	if ok {
+		return v, true
+	}
+	v, ok = t.getL2(k)
+	if ok {
+		t.l1.Put(k, v)
+	}
+	return v, ok
+}
+
+// Put adds value to L1 and also L2 unless eviction spill is enabled.
+func (t *Tiered) Put(k Key, v Value) {
+	t.l1.Put(k, v)
+	if !t.l1.spill {
+		t.l2.Put(k, v)
+	}
+}
+
+// Invalidate discards cached value of k in both levels, including entries
+// waiting to be spilled to L2.
+func (t *Tiered) Invalidate(k Key) {
+	t.l1.Invalidate(k)
+	if t.spills == nil {
+		t.l2.Invalidate(k)
+		return
+	}
+	t.pendingMu.Lock()
+	if n := t.pending[k]; n > 0 {
+		t.stale[k] = n
+	}
+	t.pendingMu.Unlock()
+	t.spillMu.Lock()
+	t.l2.Invalidate(k)
+	t.spillMu.Unlock()
+}
+
+// InvalidateAll discards all entries in both levels, including entries
+// waiting to be spilled to L2.
+func (t *Tiered) InvalidateAll() {
+	t.l1.InvalidateAll()
+	if t.spills == nil {
+		t.l2.InvalidateAll()
+		return
+	}
+	t.pendingMu.Lock()
+	for k, n := range t.pending {
+		t.stale[k] = n
+	}
+	t.pendingMu.Unlock()
+	t.spillMu.Lock()
+	t.l2.InvalidateAll()
+	t.spillMu.Unlock()
+}
+
+// Get returns value associated with k from L1 or loads it from L2 or the
+// underlying loader when it is not present. Without a loader function, it
+// returns ErrNotFound when the value is not present in both levels.
+func (t *Tiered) Get(k Key) (Value, error) {
+	if t.loader == nil {
+		if v, ok := t.GetIfPresent(k); ok {
+			return v, nil
+		}
+		return nil, ErrNotFound
+	}
+	return t.l1.Get(k)
+}
+
+// Refresh reloads value for k in L1.
+func (t *Tiered) Refresh(k Key) {
+	t.l1.Refresh(k)
+}
+
+// Stats copies L1 cache stats to s.
+func (t *Tiered) Stats(s *Stats) {
+	t.l1.Stats(s)
+}
+
+// TierStats copies stats of each level to l1 and l2.
+// Stats of L2 only contain hits and misses of lookups made by this cache.
+func (t *Tiered) TierStats(l1, l2 *Stats) {
+	t.l1.Stats(l1)
+	t.l2Stats.Snapshot(l2)
+}
+
+// Close closes L1 cache and waits for queued spilled entries to be written.
+// L2 cache is not closed as it may be shared.
+func (t *Tiered) Close() error {
+	err := t.l1.Close()
+	t.closeOnce.Do(func() {
+		if t.spills != nil {
+			// L1 no longer evicts entries after closed.
+			close(t.spills)
+			t.spillWG.Wait()
+		}
+	})
+	return err
+}
+
+func (t *Tiered) getL2(k Key) (Value, bool) {
+	v, ok := t.l2.GetIfPresent(k)
+	if ok {
+		t.l2Stats.RecordHits(1)
+	} else {
+		t.l2Stats.RecordMisses(1)
+	}
+	return v, ok
+}
+
+// load retrieves value from L2 or the loader function.
+// It is used as the loader function of L1.
+func (t *Tiered) load(k Key) (Value, error) {
+	if v, ok := t.getL2(k); ok {
+		return v, nil
+	}
+	v, err := t.loader(k)
+	if err == nil && !t.l1.spill {
+		t.l2.Put(k, v)
+	}
+	return v, err
+}
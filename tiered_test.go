@@ -0,0 +1,195 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTieredGetIfPresent(t *testing.T) {
+	l2 := New()
+	defer l2.Close()
+	c := NewTiered(l2)
+	defer c.Close()
+
+	l2.Put("a", 1)
+	v, ok := c.GetIfPresent("a")
+	if !ok || v.(int) != 1 {
+		t.Fatalf("unexpected value: %v (%v)", v, ok)
+	}
+	v, ok = c.GetIfPresent("a")
+	if !ok || v.(int) != 1 {
+		t.Fatalf("unexpected value: %v (%v)", v, ok)
+	}
+	_, ok = c.GetIfPresent("b")
+	if ok {
+		t.Fatalf("unexpected value for %v", "b")
+	}
+	var st1, st2 Stats
+	c.TierStats(&st1, &st2)
+	if st1.HitCount != 1 || st1.MissCount != 2 {
+		t.Fatalf("unexpected l1 stats: %+v", st1)
+	}
+	if st2.HitCount != 1 || st2.MissCount != 1 {
+		t.Fatalf("unexpected l2 stats: %+v", st2)
+	}
+
+	c.Put("c", 3)
+	v, ok = l2.GetIfPresent("c")
+	if !ok || v.(int) != 3 {
+		t.Fatalf("unexpected l2 value: %v (%v)", v, ok)
+	}
+}
+
+func TestTieredGetWithoutLoader(t *testing.T) {
+	l2 := New()
+	defer l2.Close()
+	c := NewTiered(l2)
+	defer c.Close()
+
+	l2.Put("a", 1)
+	v, err := c.Get("a")
+	if err != nil || v.(int) != 1 {
+		t.Fatalf("unexpected get: %v %v", v, err)
+	}
+	v, err = c.Get("b")
+	if err != ErrNotFound || v != nil {
+		t.Fatalf("unexpected get: %v %v", v, err)
+	}
+}
+
+func TestTieredLoadingCache(t *testing.T) {
+	loadCount := 0
+	loader := func(k Key) (Value, error) {
+		loadCount++
+		return k, nil
+	}
+	l2 := New()
+	defer l2.Close()
+	c := NewTieredLoadingCache(l2, loader)
+	defer c.Close()
+
+	l2.Put(1, 1)
+	v, err := c.Get(1)
+	if err != nil || v.(int) != 1 {
+		t.Fatalf("unexpected get: %v %v", v, err)
+	}
+	if loadCount != 0 {
+		t.Fatalf("unexpected load count: %v", loadCount)
+	}
+	v, err = c.Get(2)
+	if err != nil || v.(int) != 2 {
+		t.Fatalf("unexpected get: %v %v", v, err)
+	}
+	if loadCount != 1 {
+		t.Fatalf("unexpected load count: %v", loadCount)
+	}
+	v, ok := l2.GetIfPresent(2)
+	if !ok || v.(int) != 2 {
+		t.Fatalf("unexpected l2 value: %v (%v)", v, ok)
+	}
+
+	c.Invalidate(1)
+	_, ok = l2.GetIfPresent(1)
+	if ok {
+		t.Fatalf("expect l2 value invalidated")
+	}
+}
+
+func TestTieredEvictionSpill(t *testing.T) {
+	removed := make(chan Key, 2)
+	remFunc := func(k Key, v Value) {
+		removed <- k
+	}
+	l2 := New()
+	defer l2.Close()
+	c := NewTiered(l2, WithMaximumSize(1), WithEvictionSpill(),
+		WithRemovalListener(remFunc))
+	defer c.Close()
+
+	c.Put(1, 1)
+	_, ok := l2.GetIfPresent(1)
+	if ok {
+		t.Fatalf("unexpected l2 value for %v", 1)
+	}
+	c.Put(2, 2)
+	if k := <-removed; k.(int) != 1 {
+		t.Fatalf("unexpected removed key: %v", k)
+	}
+	// Evicted entries are written to L2 asynchronously.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		v, ok := l2.GetIfPresent(1)
+		if ok {
+			if v.(int) != 1 {
+				t.Fatalf("unexpected l2 value: %v", v)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("entry is not spilled to l2")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// blockingCache is a Cache which Put blocks until unblock is closed.
+type blockingCache struct {
+	Cache
+	unblock chan struct{}
+}
+
+func (c *blockingCache) Put(k Key, v Value) {
+	<-c.unblock
+	c.Cache.Put(k, v)
+}
+
+func TestTieredEvictionSpillNonBlocking(t *testing.T) {
+	l2 := &blockingCache{Cache: New(), unblock: make(chan struct{})}
+	defer l2.Close()
+	c := NewTiered(l2, WithMaximumSize(1), WithEvictionSpill())
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*spillQueueSize; i++ {
+			c.Put(i, i)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("L1 is blocked by L2")
+	}
+	close(l2.unblock)
+	c.Close()
+	c.Close()
+	if l2.Cache.(*localCache).cache.len() == 0 {
+		t.Fatalf("expect spilled entries in l2")
+	}
+}
+
+func TestTieredEvictionSpillInvalidate(t *testing.T) {
+	removed := make(chan Key, 1)
+	l2 := &blockingCache{Cache: New(), unblock: make(chan struct{})}
+	defer l2.Close()
+	c := NewTiered(l2, WithMaximumSize(1), WithEvictionSpill(),
+		WithRemovalListener(func(k Key, v Value) {
+			removed <- k
+		}))
+	c.Put(1, 1)
+	c.Put(2, 2)
+	k := <-removed
+	// The evicted entry is invalidated while it is queued or being written
+	// to L2, which may hold the invalidation until it is unblocked.
+	done := make(chan struct{})
+	go func() {
+		c.Invalidate(k)
+		close(done)
+	}()
+	close(l2.unblock)
+	<-done
+	c.Close()
+	if _, ok := l2.GetIfPresent(k); ok {
+		t.Fatalf("unexpected l2 value for %v", k)
+	}
+}
@@ -1,5 +1,9 @@
 package cache
 
+import (
+	"github.com/goburrow/cache/sketch"
+)
+
 const (
 	samplesMultiplier        = 8
 	insertionsMultiplier     = 2
@@ -12,8 +16,8 @@ const (
 // and Bloom Filter as a Doorkeeper and Segmented LRU for long term retention.
 // See https://arxiv.org/pdf/1512.00727v2.pdf
 type tinyLFU struct {
-	filter  bloomFilter    // 1bit counter
-	counter countMinSketch // 4bit counter
+	filter  *sketch.BloomFilter     // 1bit counter
+	counter *sketch.FrequencySketch // 4bit counter
 
 	additions int
 	samples   int
@@ -23,15 +27,20 @@ type tinyLFU struct {
 }
 
 func (l *tinyLFU) init(c *cache, cap int) {
+	l.initSketch(cap)
+	lruCap := int(float64(cap) * admissionRatio)
+	l.lru.init(c, lruCap)
+	l.slru.init(c, cap-lruCap)
+}
+
+// initSketch initializes the frequency sketch for the given capacity.
+func (l *tinyLFU) initSketch(cap int) {
 	if cap > 0 {
 		// Only enable doorkeeper when capacity is finite.
 		l.samples = samplesMultiplier * cap
-		l.filter.init(insertionsMultiplier*cap, falsePositiveProbability)
-		l.counter.init(countersMultiplier * cap)
+		l.filter = sketch.NewBloomFilter(insertionsMultiplier*cap, falsePositiveProbability)
+		l.counter = sketch.NewFrequencySketch(countersMultiplier*cap, sketch.DefaultDepth)
 	}
-	lruCap := int(float64(cap) * admissionRatio)
-	l.lru.init(c, lruCap)
-	l.slru.init(c, cap-lruCap)
 }
 
 func (l *tinyLFU) write(en *entry) *entry {
@@ -79,19 +88,19 @@ func (l *tinyLFU) increase(h uint64) {
 	}
 	l.additions++
 	if l.additions >= l.samples {
-		l.filter.reset()
-		l.counter.reset()
+		l.filter.Reset()
+		l.counter.Reset()
 		l.additions = 0
 	}
-	if l.filter.put(h) {
-		l.counter.add(h)
+	if l.filter.Put(h) {
+		l.counter.Add(h)
 	}
 }
 
 // estimate estimates frequency of the given hash value.
 func (l *tinyLFU) estimate(h uint64) uint8 {
-	freq := l.counter.estimate(h)
-	if l.filter.contains(h) {
+	freq := l.counter.Estimate(h)
+	if l.filter.Contains(h) {
 		freq++
 	}
 	return freq
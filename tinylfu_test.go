@@ -59,6 +59,7 @@ func (t *tinyLFUTest) assertLRUEntry(k int, id uint8) {
 
 func TestTinyLFU(t *testing.T) {
 	s := tinyLFUTest{t: t}
+	s.c.init(defaultConcurrencyLevel)
 	s.lfu.init(&s.c, 200)
 	s.assertCap(200)
 	s.lfu.slru.protectedCap = 2
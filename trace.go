@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// Size of an encoded trace record: time, operation and key hash.
+	traceRecordSize = 8 + 1 + 8
+	// Number of trace records buffered before they are dropped.
+	traceBufferSize = 1024
+	// Modulus of key hashes used in trace sampling.
+	traceModulus = 1 << 24
+)
+
+// TraceOp is the cache operation of a trace record.
+type TraceOp uint8
+
+// Cache operations recorded in traces.
+const (
+	TraceGetIfPresent TraceOp = iota + 1
+	TraceGet
+	TracePut
+	TraceInvalidate
+)
+
+// String returns name of the operation.
+func (op TraceOp) String() string {
+	switch op {
+	case TraceGetIfPresent:
+		return "getIfPresent"
+	case TraceGet:
+		return "get"
+	case TracePut:
+		return "put"
+	case TraceInvalidate:
+		return "invalidate"
+	default:
+		return "unknown"
+	}
+}
+
+// TraceRecord is a cache operation recorded by WithTraceRecorder.
+type TraceRecord struct {
+	Time time.Time
+	Op   TraceOp
+	// Hash is the hash of the key in the cache. It is seeded randomly for
+	// each cache unless WithHasher is used, so hashes of different caches or
+	// processes are not comparable.
+	Hash uint64
+}
+
+// TraceReader reads trace records written by WithTraceRecorder.
+// Each record is encoded in 17 bytes: Unix time in nanoseconds (int64),
+// operation (uint8) and key hash (uint64), all in little-endian.
+type TraceReader struct {
+	r *bufio.Reader
+	b [traceRecordSize]byte
+}
+
+// NewTraceReader returns a TraceReader reading from r.
+func NewTraceReader(r io.Reader) *TraceReader {
+	return &TraceReader{
+		r: bufio.NewReader(r),
+	}
+}
+
+// Read reads the next record. It returns io.EOF when there are no more records.
+func (r *TraceReader) Read() (TraceRecord, error) {
+	_, err := io.ReadFull(r.r, r.b[:])
+	if err != nil {
+		return TraceRecord{}, err
+	}
+	return TraceRecord{
+		Time: time.Unix(0, int64(binary.LittleEndian.Uint64(r.b[:]))),
+		Op:   TraceOp(r.b[8]),
+		Hash: binary.LittleEndian.Uint64(r.b[9:]),
+	}, nil
+}
+
+// traceRecorder writes sampled trace records in a separate goroutine.
+// Records are dropped instead of blocking callers when the buffer is full.
+type traceRecorder struct {
+	w         io.Writer
+	threshold uint64
+	records   chan TraceRecord
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func newTraceRecorder(w io.Writer, rate float64) *traceRecorder {
+	if rate <= 0 || rate > 1 {
+		rate = 1
+	}
+	return &traceRecorder{
+		w:         w,
+		threshold: uint64(rate * traceModulus),
+		records:   make(chan TraceRecord, traceBufferSize),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// start starts the writing goroutine if it has not been started.
+func (r *traceRecorder) start() {
+	r.startOnce.Do(func() {
+		go r.run()
+	})
+}
+
+// close stops the writing goroutine after flushing buffered records.
+func (r *traceRecorder) close() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+		<-r.done
+	})
+}
+
+// record records operation op of the key hash h if the key is sampled.
+func (r *traceRecorder) record(op TraceOp, h uint64) {
+	if r.threshold < traceModulus && fmix64(h)%traceModulus >= r.threshold {
+		return
+	}
+	select {
+	case r.records <- TraceRecord{Time: currentTime(), Op: op, Hash: h}:
+	default:
+	}
+}
+
+func (r *traceRecorder) run() {
+	defer close(r.done)
+	w := bufio.NewWriter(r.w)
+	var b [traceRecordSize]byte
+	write := func(rec TraceRecord) {
+		binary.LittleEndian.PutUint64(b[:], uint64(rec.Time.UnixNano()))
+		b[8] = byte(rec.Op)
+		binary.LittleEndian.PutUint64(b[9:], rec.Hash)
+		w.Write(b[:])
+	}
+	for {
+		select {
+		case rec := <-r.records:
+			write(rec)
+			if len(r.records) == 0 {
+				w.Flush()
+			}
+		case <-r.stop:
+			for {
+				select {
+				case rec := <-r.records:
+					write(rec)
+				default:
+					w.Flush()
+					return
+				}
+			}
+		}
+	}
+}
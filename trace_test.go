@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func readTrace(t *testing.T, b []byte) []TraceRecord {
+	var records []TraceRecord
+	r := NewTraceReader(bytes.NewReader(b))
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Helper()
+			t.Fatal(err)
+		}
+		records = append(records, rec)
+	}
+}
+
+func TestTraceRecorder(t *testing.T) {
+	mockTime := newMockTime()
+	currentTime = mockTime.now
+	defer func() {
+		currentTime = time.Now
+	}()
+
+	var b bytes.Buffer
+	c := NewLoadingCache(func(k Key) (Value, error) {
+		return k, nil
+	}, WithTraceRecorder(&b, 1))
+	c.GetIfPresent(1)
+	c.Put(1, 1)
+	c.Get("a")
+	c.Invalidate(1)
+	c.Close()
+
+	h := &c.(*localCache).hasher
+	records := readTrace(t, b.Bytes())
+	want := []TraceRecord{
+		{Op: TraceGetIfPresent, Hash: h.sum(1)},
+		{Op: TracePut, Hash: h.sum(1)},
+		{Op: TraceGet, Hash: h.sum("a")},
+		{Op: TraceInvalidate, Hash: h.sum(1)},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+	for i := range want {
+		if records[i].Op != want[i].Op || records[i].Hash != want[i].Hash ||
+			!records[i].Time.Equal(mockTime.now()) {
+			t.Fatalf("unexpected record: %+v, want: %+v", records[i], want[i])
+		}
+	}
+	if _, err := NewTraceReader(bytes.NewReader(make([]byte, 10))).Read(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestTraceRecorderSampling(t *testing.T) {
+	var b bytes.Buffer
+	c := New(WithTraceRecorder(&b, 0.1))
+	for i := 0; i < 10000; i++ {
+		c.GetIfPresent(i)
+		c.Put(i, i)
+		// Give the recorder a chance to catch up.
+		if i%100 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	c.Close()
+
+	records := readTrace(t, b.Bytes())
+	if len(records) < 1000 || len(records) > 3000 {
+		t.Fatalf("unexpected number of records: %d", len(records))
+	}
+	ops := make(map[uint64]int)
+	for _, rec := range records {
+		ops[rec.Hash]++
+	}
+	for h, n := range ops {
+		if n != 2 {
+			t.Fatalf("unexpected operations of %d: %d", h, n)
+		}
+	}
+}
+
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(b []byte) (int, error) {
+	<-w.release
+	return len(b), nil
+}
+
+func TestTraceRecorderNonBlocking(t *testing.T) {
+	w := &blockingWriter{release: make(chan struct{})}
+	c := New(WithTraceRecorder(w, 1))
+	for i := 0; i < 10*traceBufferSize; i++ {
+		c.GetIfPresent(i)
+	}
+	close(w.release)
+	c.Close()
+}
+
+func TestTraceRecorderPerCache(t *testing.T) {
+	var b1, b2 bytes.Buffer
+	opt := WithTraceRecorder(&b1, 1)
+	c1 := New(opt)
+	c2 := New(opt)
+	// Closing a cache does not stop recording of the other.
+	c1.Put(1, 1)
+	c1.Close()
+	c2.Put(2, 2)
+	c2.Close()
+	if n := len(readTrace(t, b1.Bytes())); n != 2 {
+		t.Fatalf("unexpected number of records: %d", n)
+	}
+
+	c := New(WithTraceRecorder(&b2, 1), WithShards(4))
+	for i := 0; i < 100; i++ {
+		c.Put(i, i)
+	}
+	c.Close()
+	if n := len(readTrace(t, b2.Bytes())); n != 100 {
+		t.Fatalf("unexpected number of records: %d", n)
+	}
+}
@@ -0,0 +1,175 @@
+package traces
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// Hit rate of the adaptive policy may be lower than the better of LRU-like
+// and LFU-like windows by this much as the hill climber is noisy.
+const adaptiveTolerance = 0.03
+
+// adaptiveTraces are synthetic traces which favor opposite window sizes.
+// Their lengths are proportional to the cache size so the window converges.
+var adaptiveTraces = []struct {
+	name     string
+	requests func(cacheSize int) []Request
+}{
+	// Half of requests are for new keys, the others are for keys requested
+	// recently. A large window (LRU) performs best.
+	{"recency", func(cacheSize int) []Request {
+		r := rand.New(rand.NewSource(1))
+		reqs := make([]Request, 2000*cacheSize)
+		window := 2 * cacheSize
+		next := 0
+		for i := range reqs {
+			if next < window || r.Intn(2) == 0 {
+				reqs[i].Key = uint64(next)
+				next++
+			} else {
+				reqs[i].Key = uint64(next - 1 - r.Intn(window))
+			}
+		}
+		return reqs
+	}},
+	// Half of requests are Zipf distributed, the others are one-hit wonders.
+	// A small window (TinyLFU) performs best.
+	{"frequency", func(cacheSize int) []Request {
+		r := rand.New(rand.NewSource(1))
+		z := rand.NewZipf(r, 1.01, 1, 1<<16-1)
+		reqs := make([]Request, 2000*cacheSize)
+		for i := range reqs {
+			if r.Intn(2) == 0 {
+				reqs[i].Key = z.Uint64()
+			} else {
+				reqs[i].Key = 1<<32 + uint64(i)
+			}
+		}
+		return reqs
+	}},
+}
+
+// adaptiveReporter reports to r and keeps stats at the middle and the end of
+// the trace, so the hit rate after the window converges can be measured.
+type adaptiveReporter struct {
+	r         Reporter
+	total     uint64
+	final     bool // only report final stats to r
+	mid, last Stats
+}
+
+func (r *adaptiveReporter) Report(st Stats, opt options) {
+	if st.RequestCount() <= r.total/2 {
+		r.mid = st
+	}
+	r.last = st
+	if !r.final || st.RequestCount() == r.total {
+		r.r.Report(st, opt)
+	}
+}
+
+// hitRate returns hit rate of the second half of the trace.
+func (r *adaptiveReporter) hitRate() float64 {
+	return float64(r.last.HitCount-r.mid.HitCount) / float64(r.last.RequestCount()-r.mid.RequestCount())
+}
+
+// testAdaptive replays reqs with LRU, TinyLFU and adaptive policies, and
+// checks the adaptive one reaches the better of the others, which is
+// expected to be clearly better than the worse one.
+func testAdaptive(t *testing.T, reqs []Request, opt options, reporters map[string]Reporter) {
+	hitRates := adaptiveHitRates(reqs, opt, reporters)
+	best, worst := hitRates["lru"], hitRates["tinylfu"]
+	if best < worst {
+		best, worst = worst, best
+	}
+	if hitRates["adaptive"] < best-adaptiveTolerance || best-worst < 2*adaptiveTolerance {
+		t.Fatalf("unexpected hit rates of cache size %d: %+v", opt.cacheSize, hitRates)
+	}
+}
+
+// adaptiveHitRates returns hit rates of the second half of reqs with LRU,
+// TinyLFU and adaptive policies.
+func adaptiveHitRates(reqs []Request, opt options, reporters map[string]Reporter) map[string]float64 {
+	hitRates := make(map[string]float64)
+	for _, p := range []string{"lru", "tinylfu", "adaptive"} {
+		r := &adaptiveReporter{
+			r:     reporters[p],
+			total: uint64(len(reqs)),
+			final: opt.reportInterval == 0,
+		}
+		o := opt
+		o.policy = p
+		if o.reportInterval == 0 {
+			o.reportInterval = len(reqs) / 2
+		}
+		benchmarkCache(requestsProvider(reqs), r, o)
+		hitRates[p] = r.hitRate()
+	}
+	return hitRates
+}
+
+// newAdaptiveReporters returns reporters writing to files of each policy.
+func newAdaptiveReporters(t *testing.T, prefix string) map[string]Reporter {
+	reporters := make(map[string]Reporter)
+	for _, p := range []string{"lru", "tinylfu", "adaptive"} {
+		w, err := os.Create(prefix + "-" + p + ".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { w.Close() })
+		reporters[p] = NewReporter(w)
+	}
+	return reporters
+}
+
+func TestRequestAdaptive(t *testing.T) {
+	for _, tr := range adaptiveTraces {
+		tr := tr
+		t.Run(tr.name, func(t *testing.T) {
+			t.Parallel()
+			opt := options{
+				cacheSize:      100,
+				reportInterval: 1000,
+			}
+			testAdaptive(t, tr.requests(opt.cacheSize), opt,
+				newAdaptiveReporters(t, "request_"+tr.name))
+		})
+	}
+}
+
+func TestSizeAdaptive(t *testing.T) {
+	for _, tr := range adaptiveTraces {
+		tr := tr
+		t.Run(tr.name, func(t *testing.T) {
+			t.Parallel()
+			reporters := newAdaptiveReporters(t, "size_"+tr.name)
+			for size := 50; size <= 200; size += size {
+				opt := options{
+					cacheSize: size,
+				}
+				testAdaptive(t, tr.requests(size), opt, reporters)
+			}
+		})
+	}
+}
+
+func TestZipfAdaptive(t *testing.T) {
+	// Same trace and cache sizes as TestSizeZipf, which writes the reports.
+	reqs := readRequests(NewZipfProvider(1.01, 100000), 0)
+	reporters := make(map[string]Reporter)
+	for _, p := range []string{"lru", "tinylfu", "adaptive"} {
+		reporters[p] = NewReporter(ioutil.Discard)
+	}
+	for size := 250; size <= 4000; size += size {
+		opt := options{
+			cacheSize: size,
+		}
+		hitRates := adaptiveHitRates(reqs, opt, reporters)
+		if hitRates["adaptive"] < hitRates["lru"]-adaptiveTolerance ||
+			hitRates["adaptive"] < hitRates["tinylfu"]-adaptiveTolerance {
+			t.Fatalf("unexpected hit rates of cache size %d: %+v", size, hitRates)
+		}
+	}
+}
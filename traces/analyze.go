@@ -0,0 +1,198 @@
+package traces
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+const (
+	// Minimum number of sequential keys considered as a scan.
+	minScanLength = 16
+	// Minimum frequency of keys used in Zipf alpha estimation.
+	minZipfFrequency = 10
+)
+
+// Analysis is the characteristics of a trace.
+type Analysis struct {
+	Requests   uint64 `json:"requests"`
+	UniqueKeys int    `json:"uniqueKeys"`
+	// OneHitWonders is the number of keys requested only once.
+	OneHitWonders     int     `json:"oneHitWonders"`
+	OneHitWonderRatio float64 `json:"oneHitWonderRatio"`
+	// ReuseDistances is the histogram of LRU stack distances in power of two
+	// buckets. First requests of keys are not included.
+	ReuseDistances []ReuseBucket `json:"reuseDistances"`
+	// ZipfAlpha is the skew estimated from the rank-frequency distribution.
+	ZipfAlpha float64 `json:"zipfAlpha"`
+	// WorkingSets is the number of unique keys in each Window requests.
+	Window      int   `json:"window"`
+	WorkingSets []int `json:"workingSets"`
+	// Scans is the number of runs of sequential keys.
+	Scans        int     `json:"scans"`
+	ScanRequests uint64  `json:"scanRequests"`
+	ScanRatio    float64 `json:"scanRatio"`
+}
+
+// ReuseBucket is the number of requests whose reuse distance is in
+// [Distance, 2*Distance).
+type ReuseBucket struct {
+	Distance int    `json:"distance"`
+	Count    uint64 `json:"count"`
+}
+
+// Analyze analyzes get requests in at most maxItems requests, or all
+// requests if maxItems is not positive, of p. Working sets are measured for
+// every window requests.
+func Analyze(p Provider, window, maxItems int) *Analysis {
+	if window <= 0 {
+		panic("invalid window size")
+	}
+	a := &Analysis{Window: window}
+	var (
+		counts = make(map[uint64]uint64)
+		stack  = newLRUStack()
+		reuse  []uint64
+		ws     = make(map[uint64]struct{})
+		prev   uint64
+		run    int
+	)
+	replay(p, maxItems, func(req Request) {
+		if req.Op != OpGet {
+			return
+		}
+		k := req.Key
+		a.Requests++
+		counts[k]++
+		// Reuse distance
+		if d := stack.access(k); d > 0 {
+			b := bucketOf(d)
+			for len(reuse) <= b {
+				reuse = append(reuse, 0)
+			}
+			reuse[b]++
+		}
+		// Working set
+		ws[k] = struct{}{}
+		if a.Requests%uint64(window) == 0 {
+			a.WorkingSets = append(a.WorkingSets, len(ws))
+			ws = make(map[uint64]struct{})
+		}
+		// Scan
+		if run > 0 && k == prev+1 {
+			run++
+		} else {
+			a.addScan(run)
+			run = 1
+		}
+		prev = k
+	})
+	a.addScan(run)
+	if len(ws) > 0 {
+		a.WorkingSets = append(a.WorkingSets, len(ws))
+	}
+	a.UniqueKeys = len(counts)
+	for _, c := range counts {
+		if c == 1 {
+			a.OneHitWonders++
+		}
+	}
+	if a.UniqueKeys > 0 {
+		a.OneHitWonderRatio = float64(a.OneHitWonders) / float64(a.UniqueKeys)
+	}
+	if a.Requests > 0 {
+		a.ScanRatio = float64(a.ScanRequests) / float64(a.Requests)
+	}
+	for b, c := range reuse {
+		a.ReuseDistances = append(a.ReuseDistances, ReuseBucket{Distance: 1 << uint(b), Count: c})
+	}
+	a.ZipfAlpha = zipfAlpha(counts)
+	return a
+}
+
+func (a *Analysis) addScan(n int) {
+	if n >= minScanLength {
+		a.Scans++
+		a.ScanRequests += uint64(n)
+	}
+}
+
+// WriteText writes the analysis in human-readable text to w.
+func (a *Analysis) WriteText(w io.Writer) error {
+	ew := &errWriter{w: w}
+	ew.printf("Requests:        %d\n", a.Requests)
+	ew.printf("Unique keys:     %d\n", a.UniqueKeys)
+	ew.printf("One-hit wonders: %d (%.4f)\n", a.OneHitWonders, a.OneHitWonderRatio)
+	ew.printf("Zipf alpha:      %.4f\n", a.ZipfAlpha)
+	ew.printf("Scans:           %d (%.4f of requests)\n", a.Scans, a.ScanRatio)
+	if len(a.WorkingSets) > 0 {
+		min, max, total := a.WorkingSets[0], a.WorkingSets[0], 0
+		for _, n := range a.WorkingSets {
+			if n < min {
+				min = n
+			}
+			if n > max {
+				max = n
+			}
+			total += n
+		}
+		ew.printf("Working set:     min %d, avg %d, max %d per %d requests\n",
+			min, total/len(a.WorkingSets), max, a.Window)
+	}
+	ew.printf("Reuse distances:\n")
+	for _, b := range a.ReuseDistances {
+		ew.printf("  %10d-%-10d %d\n", b.Distance, 2*b.Distance-1, b.Count)
+	}
+	return ew.err
+}
+
+// errWriter keeps the first error of writes.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *errWriter) printf(format string, args ...interface{}) {
+	if w.err == nil {
+		_, w.err = fmt.Fprintf(w.w, format, args...)
+	}
+}
+
+// bucketOf returns the power of two bucket of distance d.
+func bucketOf(d int) int {
+	b := 0
+	for d > 1 {
+		d >>= 1
+		b++
+	}
+	return b
+}
+
+// zipfAlpha estimates the Zipf skew by fitting log(frequency) against
+// log(rank) with least squares. Rarely requested keys are excluded as the
+// tail of a sampled distribution is flat.
+func zipfAlpha(counts map[uint64]uint64) float64 {
+	freqs := make([]uint64, 0, len(counts))
+	for _, c := range counts {
+		if c >= minZipfFrequency {
+			freqs = append(freqs, c)
+		}
+	}
+	if len(freqs) < 2 {
+		return 0
+	}
+	sort.Slice(freqs, func(i, j int) bool { return freqs[i] > freqs[j] })
+	var sx, sy, sxx, sxy float64
+	for i, f := range freqs {
+		x := math.Log(float64(i + 1))
+		y := math.Log(float64(f))
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+	n := float64(len(freqs))
+	slope := (n*sxy - sx*sy) / (n*sxx - sx*sx)
+	return -slope
+}
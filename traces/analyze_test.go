@@ -0,0 +1,50 @@
+package traces
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	var keys []uint64
+	for i := 0; i < 20; i++ {
+		keys = append(keys, uint64(100+i))
+	}
+	keys = append(keys, 1, 5, 1, 9, 5, 1)
+	a := Analyze(newSliceProvider(keys...), 10, 0)
+	if a.Requests != 26 || a.UniqueKeys != 23 || a.OneHitWonders != 21 {
+		t.Fatalf("unexpected analysis: %+v", a)
+	}
+	if a.Scans != 1 || a.ScanRequests != 20 {
+		t.Fatalf("unexpected scans: %+v", a)
+	}
+	// Distances: 1=2, 5=3, 1=3
+	want := []ReuseBucket{{1, 0}, {2, 3}}
+	if len(a.ReuseDistances) != len(want) || a.ReuseDistances[0] != want[0] || a.ReuseDistances[1] != want[1] {
+		t.Fatalf("unexpected reuse distances: %+v", a.ReuseDistances)
+	}
+	if len(a.WorkingSets) != 3 || a.WorkingSets[0] != 10 || a.WorkingSets[2] != 3 {
+		t.Fatalf("unexpected working sets: %+v", a.WorkingSets)
+	}
+	var w bytes.Buffer
+	if err := a.WriteText(&w); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(w.String(), "Unique keys:     23\n") {
+		t.Fatalf("unexpected text: %s", w.String())
+	}
+}
+
+func TestAnalyzeZipf(t *testing.T) {
+	for _, s := range []float64{1.01, 1.5} {
+		a := Analyze(NewZipfProvider(s, 100000), 10000, 0)
+		if math.Abs(a.ZipfAlpha-s) > 0.1 {
+			t.Fatalf("unexpected zipf alpha: %v, want: %v", a.ZipfAlpha, s)
+		}
+		if a.Scans != 0 || len(a.WorkingSets) != 10 {
+			t.Fatalf("unexpected analysis: %+v", a)
+		}
+	}
+}
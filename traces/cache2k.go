@@ -2,13 +2,13 @@ package traces
 
 import (
 	"bufio"
-	"context"
 	"encoding/binary"
 	"io"
 )
 
 type cache2kProvider struct {
 	r *bufio.Reader
+	b [4]byte
 }
 
 // NewCache2kProvider returns a Provider which items are from traces
@@ -19,20 +19,16 @@ func NewCache2kProvider(r io.Reader) Provider {
 	}
 }
 
-func (p *cache2kProvider) Provide(ctx context.Context, keys chan<- interface{}) {
-	defer close(keys)
-
-	v := make([]byte, 4)
-	for {
-		_, err := p.r.Read(v)
+func (p *cache2kProvider) Read(reqs []Request) (int, error) {
+	for i := range reqs {
+		_, err := io.ReadFull(p.r, p.b[:])
 		if err != nil {
-			return
-		}
-		k := binary.LittleEndian.Uint32(v)
-		select {
-		case <-ctx.Done():
-			return
-		case keys <- k:
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return i, err
 		}
+		reqs[i] = Request{Key: uint64(binary.LittleEndian.Uint32(p.b[:]))}
 	}
+	return len(reqs), nil
 }
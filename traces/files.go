@@ -82,12 +82,14 @@ func (f *bzip2File) Close() error {
 	return f.f.Close()
 }
 
-type filesReader struct {
+// FilesReader reads multiple trace files sequentially. Files with extension
+// .gz or .bz2 are decompressed.
+type FilesReader struct {
 	io.Reader
 	files []readSeekCloser
 }
 
-func openFilesGlob(pattern string) (*filesReader, error) {
+func openFilesGlob(pattern string) (*FilesReader, error) {
 	files, err := filepath.Glob(pattern)
 	if err != nil {
 		return nil, err
@@ -95,11 +97,12 @@ func openFilesGlob(pattern string) (*filesReader, error) {
 	if len(files) == 0 {
 		return nil, fmt.Errorf("%s not found", pattern)
 	}
-	return openFiles(files...)
+	return OpenFiles(files...)
 }
 
-func openFiles(files ...string) (*filesReader, error) {
-	r := &filesReader{}
+// OpenFiles opens the given files for reading.
+func OpenFiles(files ...string) (*FilesReader, error) {
+	r := &FilesReader{}
 	r.files = make([]readSeekCloser, 0, len(files))
 	readers := make([]io.Reader, 0, len(files))
 	for _, name := range files {
@@ -123,7 +126,8 @@ func openFiles(files ...string) (*filesReader, error) {
 	return r, nil
 }
 
-func (r *filesReader) Close() error {
+// Close closes all files.
+func (r *FilesReader) Close() error {
 	var err error
 	for _, f := range r.files {
 		e := f.Close()
@@ -134,7 +138,8 @@ func (r *filesReader) Close() error {
 	return err
 }
 
-func (r *filesReader) Reset() error {
+// Reset seeks all files to the beginning so they can be read again.
+func (r *FilesReader) Reset() error {
 	readers := make([]io.Reader, 0, len(r.files))
 	for _, f := range r.files {
 		_, err := f.Seek(0, 0)
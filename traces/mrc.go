@@ -0,0 +1,213 @@
+package traces
+
+const (
+	// shardsModulus is the modulus of key hashes used in SHARDS sampling.
+	shardsModulus = 1 << 24
+	// minStackCompaction is the minimum number of positions of an lruStack
+	// before it is compacted.
+	minStackCompaction = 1024
+)
+
+// MRC is a miss-ratio curve of an LRU cache computed from stack distances.
+// See Mattson et al., "Evaluation techniques for storage hierarchies" and
+// Waldspurger et al., "Efficient MRC Construction with SHARDS".
+type MRC struct {
+	hits     []uint64 // hits[d] is number of requests with stack distance d+1
+	requests uint64   // number of sampled requests
+	total    uint64   // number of all requests
+	rate     float64
+	cum      []uint64
+}
+
+// NewMRC computes the exact LRU miss-ratio curve from at most maxItems
+// requests, or all requests if maxItems is not positive, of p.
+// Only get requests are counted, but sets move keys to the top of the LRU
+// stack and deletes remove them, as in the simulated caches. The curve is
+// approximate when there are deletes, since slots freed by deletes are not
+// accounted for cache sizes smaller than the stack.
+func NewMRC(p Provider, maxItems int) *MRC {
+	return NewSampledMRC(p, 1, maxItems)
+}
+
+// NewSampledMRC computes an approximate LRU miss-ratio curve by sampling keys
+// whose hashes fall under the given rate, which should be in (0, 1].
+// Stack distances of sampled keys are scaled by 1/rate, and the difference
+// between expected and actual number of sampled requests is counted in the
+// smallest distance (SHARDS-adj).
+func NewSampledMRC(p Provider, rate float64, maxItems int) *MRC {
+	if rate <= 0 || rate > 1 {
+		panic("invalid sampling rate")
+	}
+	threshold := uint64(rate * shardsModulus)
+	m := &MRC{rate: rate}
+	stack := newLRUStack()
+	replay(p, maxItems, func(req Request) {
+		k := req.Key
+		if req.Op == OpGet {
+			m.total++
+		}
+		if rate < 1 && mix64(k)%shardsModulus >= threshold {
+			return
+		}
+		if req.Op == OpDelete {
+			stack.remove(k)
+			return
+		}
+		// Both gets and sets move the key to the top of the stack.
+		d := stack.access(k)
+		if req.Op == OpGet {
+			m.requests++
+			if d > 0 {
+				for len(m.hits) < d {
+					m.hits = append(m.hits, 0)
+				}
+				m.hits[d-1]++
+			}
+		}
+	})
+	m.cum = make([]uint64, len(m.hits))
+	var sum uint64
+	for d, n := range m.hits {
+		sum += n
+		m.cum[d] = sum
+	}
+	return m
+}
+
+// Requests returns number of requests (sampled) used to compute the curve.
+func (m *MRC) Requests() uint64 {
+	return m.requests
+}
+
+// MissRatio returns the miss ratio of an LRU cache of the given size, or 0
+// if there are no requests like cache.Stats.
+func (m *MRC) MissRatio(cacheSize int) float64 {
+	if m.requests == 0 {
+		return 0
+	}
+	d := int(float64(cacheSize) * m.rate)
+	if d > len(m.cum) {
+		d = len(m.cum)
+	}
+	if d <= 0 {
+		return 1
+	}
+	if m.rate == 1 {
+		return float64(m.requests-m.cum[d-1]) / float64(m.requests)
+	}
+	expected := float64(m.total) * m.rate
+	hits := float64(m.cum[d-1]) + expected - float64(m.requests)
+	r := 1 - hits/expected
+	if r < 0 {
+		return 0
+	}
+	if r > 1 {
+		return 1
+	}
+	return r
+}
+
+// HitRate returns the hit rate of an LRU cache of the given size, or 1 if
+// there are no requests.
+func (m *MRC) HitRate(cacheSize int) float64 {
+	return 1 - m.MissRatio(cacheSize)
+}
+
+// lruStack computes LRU stack distances. Each access appends a position
+// marked in a Fenwick tree, so the distance of a key is the number of marks
+// from its last position. Positions are renumbered when most of them are
+// unmarked, so memory is bounded by the number of distinct keys.
+type lruStack struct {
+	marks fenwick
+	last  map[uint64]int // last position of each key
+}
+
+func newLRUStack() *lruStack {
+	return &lruStack{
+		last: make(map[uint64]int),
+	}
+}
+
+// access moves k to the top of the stack and returns its previous stack
+// distance, or 0 if k was not in the stack.
+func (s *lruStack) access(k uint64) int {
+	if len(s.marks) >= minStackCompaction && len(s.marks) >= 2*len(s.last) {
+		s.compact()
+	}
+	d := 0
+	if t, ok := s.last[k]; ok {
+		// Number of distinct keys requested since the last request of k,
+		// including k.
+		d = s.marks.sum(len(s.marks)) - s.marks.sum(t-1)
+		s.marks.add(t, -1)
+	}
+	s.marks.append(1)
+	s.last[k] = len(s.marks)
+	return d
+}
+
+// remove removes k from the stack.
+func (s *lruStack) remove(k uint64) {
+	if t, ok := s.last[k]; ok {
+		s.marks.add(t, -1)
+		delete(s.last, k)
+	}
+}
+
+// compact renumbers positions of keys in the stack from 1 in their order.
+func (s *lruStack) compact() {
+	keys := make([]uint64, len(s.marks))
+	used := make([]bool, len(s.marks))
+	for k, t := range s.last {
+		keys[t-1] = k
+		used[t-1] = true
+	}
+	n := 0
+	for i, k := range keys {
+		if used[i] {
+			n++
+			s.last[k] = n
+		}
+	}
+	// Every position is marked, so each node is the size of its range.
+	s.marks = s.marks[:n]
+	for i := range s.marks {
+		s.marks[i] = (i + 1) & -(i + 1)
+	}
+}
+
+// fenwick is a binary indexed tree with 1-based positions which can grow.
+type fenwick []int
+
+// sum returns sum of values at positions 1 to i.
+func (f fenwick) sum(i int) int {
+	s := 0
+	for ; i > 0; i -= i & -i {
+		s += f[i-1]
+	}
+	return s
+}
+
+// add adds v to value at position i.
+func (f fenwick) add(i int, v int) {
+	for ; i <= len(f); i += i & -i {
+		f[i-1] += v
+	}
+}
+
+// append adds a new position with value v.
+func (f *fenwick) append(v int) {
+	n := len(*f) + 1
+	s := v + f.sum(n-1) - f.sum(n-(n&-n))
+	*f = append(*f, s)
+}
+
+// mix64 is the finalizer of MurmurHash3.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,132 @@
+package traces
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/goburrow/cache"
+)
+
+func TestFenwick(t *testing.T) {
+	var f fenwick
+	for i := 1; i <= 100; i++ {
+		f.append(i)
+	}
+	f.add(10, -10)
+	for i := 0; i <= 100; i++ {
+		want := i * (i + 1) / 2
+		if i >= 10 {
+			want -= 10
+		}
+		if s := f.sum(i); s != want {
+			t.Fatalf("unexpected sum of %d: %d, want: %d", i, s, want)
+		}
+	}
+}
+
+func TestLRUStack(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	s := newLRUStack()
+	var stack []uint64 // most recent key last
+	for i := 0; i < 100000; i++ {
+		k := uint64(r.Intn(100))
+		want := 0
+		for j := len(stack) - 1; j >= 0; j-- {
+			if stack[j] == k {
+				want = len(stack) - j
+				stack = append(stack[:j], stack[j+1:]...)
+				break
+			}
+		}
+		if r.Intn(10) == 0 {
+			s.remove(k)
+			continue
+		}
+		if d := s.access(k); d != want {
+			t.Fatalf("unexpected distance of %d at %d: %d, want: %d", k, i, d, want)
+		}
+		stack = append(stack, k)
+	}
+	if len(s.marks) > 2*minStackCompaction {
+		t.Fatalf("unexpected stack size: %d", len(s.marks))
+	}
+}
+
+func TestMRC(t *testing.T) {
+	p := newSliceProvider(1, 2, 3, 1, 2, 3, 3, 4)
+	m := NewMRC(p, 0)
+	if m.Requests() != 8 {
+		t.Fatalf("unexpected requests: %d", m.Requests())
+	}
+	tests := []struct {
+		size int
+		miss float64
+	}{
+		{0, 1},
+		{1, 7.0 / 8},
+		{2, 7.0 / 8},
+		{3, 4.0 / 8},
+		{10, 4.0 / 8},
+	}
+	for _, tt := range tests {
+		if r := m.MissRatio(tt.size); r != tt.miss {
+			t.Fatalf("unexpected miss ratio of %d: %v, want: %v", tt.size, r, tt.miss)
+		}
+	}
+	m = NewMRC(newSliceProvider(), 0)
+	if m.MissRatio(10) != 0 || m.HitRate(10) != 1 {
+		t.Fatalf("unexpected empty curve: %v %v", m.MissRatio(10), m.HitRate(10))
+	}
+}
+
+func TestMRCSimulator(t *testing.T) {
+	reqs := readRequests(NewZipfProvider(1.01, 50000), 0)
+	m := NewMRC(requestsProvider(reqs), 0)
+	for _, size := range []int{10, 100, 250, 1000, 4000} {
+		s := cache.NewSimulator(cache.WithMaximumSize(size), cache.WithPolicy("lru"))
+		for _, r := range reqs {
+			if _, ok := s.Get(r.Key); !ok {
+				s.Put(r.Key, r.Key)
+			}
+		}
+		var st cache.Stats
+		s.Stats(&st)
+		if r := m.MissRatio(size); r != st.MissRate() {
+			t.Fatalf("unexpected miss ratio of %d: %v, want: %v", size, r, st.MissRate())
+		}
+	}
+}
+
+func TestMRCOps(t *testing.T) {
+	// Deleted keys are not in the stack, while sets move keys to the top.
+	p := sliceProvider{{Key: 1}, {Key: 2}, {Op: OpDelete, Key: 1}, {Key: 1},
+		{Key: 3}, {Op: OpSet, Key: 2}, {Key: 2}}
+	m := NewMRC(&p, 0)
+	if m.Requests() != 5 || m.MissRatio(10) != 4.0/5 || m.MissRatio(1) != 4.0/5 {
+		t.Fatalf("unexpected miss ratio: %v %v", m.MissRatio(10), m.MissRatio(1))
+	}
+	reqs := mixedRequests(50000)
+	m = NewMRC(requestsProvider(reqs), 0)
+	for _, size := range []int{10, 100, 1000} {
+		res := Simulate(requestsProvider(reqs), "lru", size, 0)
+		if r := m.MissRatio(size); math.Abs(r-res.Stats.MissRate()) > 0.001 {
+			t.Fatalf("unexpected miss ratio of %d: %v, want: %v", size, r, res.Stats.MissRate())
+		}
+	}
+}
+
+func TestSampledMRC(t *testing.T) {
+	reqs := readRequests(NewZipfProvider(1.01, 200000), 0)
+	exact := NewMRC(requestsProvider(reqs), 0)
+	sampled := NewSampledMRC(requestsProvider(reqs), 0.5, 0)
+	if sampled.Requests() >= exact.Requests()*3/5 {
+		t.Fatalf("unexpected sampled requests: %d", sampled.Requests())
+	}
+	for _, size := range []int{100, 500, 1000, 5000} {
+		a, b := exact.MissRatio(size), sampled.MissRatio(size)
+		if math.Abs(a-b) > 0.02 {
+			t.Fatalf("unexpected miss ratio of %d: %v, exact: %v", size, b, a)
+		}
+	}
+}
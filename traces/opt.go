@@ -0,0 +1,138 @@
+package traces
+
+import (
+	"container/heap"
+)
+
+// optPolicy is the name of Belady's optimal policy which is only available
+// in simulations as it needs to know the whole trace in advance.
+const optPolicy = "opt"
+
+// optItem is a resident key and position of its next request.
+type optItem struct {
+	key   uint64
+	next  int
+	index int
+}
+
+// optHeap is a max-heap of resident keys ordered by their next requests.
+type optHeap []*optItem
+
+func (h optHeap) Len() int           { return len(h) }
+func (h optHeap) Less(i, j int) bool { return h[i].next > h[j].next }
+
+func (h optHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *optHeap) Push(x interface{}) {
+	item := x.(*optItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *optHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// optTrace is a trace kept in memory for OPT. Only fields used by OPT are
+// stored, in separate slices to avoid padding.
+type optTrace struct {
+	keys    []uint64
+	ops     []Op
+	weights []uint32
+}
+
+// readOPTTrace reads at most maxItems requests, or all requests if maxItems
+// is not positive, from p.
+func readOPTTrace(p Provider, maxItems int) *optTrace {
+	t := &optTrace{}
+	replay(p, maxItems, func(r Request) {
+		t.keys = append(t.keys, r.Key)
+		t.ops = append(t.ops, r.Op)
+		t.weights = append(t.weights, uint32(r.weight()))
+	})
+	return t
+}
+
+// nextUses returns position of the next get request of the same key for
+// each request in the trace, or the trace length if the key is not read
+// again before it is set or deleted, as the cached value is useless then.
+func (t *optTrace) nextUses() []int {
+	n := len(t.keys)
+	next := make([]int, n)
+	last := make(map[uint64]int)
+	for i := n - 1; i >= 0; i-- {
+		k := t.keys[i]
+		if j, ok := last[k]; ok && t.ops[j] == OpGet {
+			next[i] = j
+		} else {
+			next[i] = n
+		}
+		last[k] = i
+	}
+	return next
+}
+
+// benchmarkOPT simulates Belady's MIN which evicts the key requested
+// furthest in the future. It is the upper bound of hit rate for the trace.
+// Sets insert keys like gets do on misses, and deletes remove keys.
+func benchmarkOPT(p Provider, r Reporter, opt options) {
+	trace := readOPTTrace(p, opt.maxItems)
+	next := trace.nextUses()
+
+	resident := make(map[uint64]*optItem, opt.cacheSize)
+	h := make(optHeap, 0, opt.cacheSize+1)
+	stats := Stats{}
+	insert := func(k uint64, next int) {
+		item := &optItem{key: k, next: next}
+		resident[k] = item
+		heap.Push(&h, item)
+		if opt.cacheSize > 0 && h.Len() > opt.cacheSize {
+			// The new key is not admitted if it is the furthest one.
+			item = heap.Pop(&h).(*optItem)
+			delete(resident, item.key)
+			stats.EvictionCount++
+		}
+	}
+	for i, k := range trace.keys {
+		item, ok := resident[k]
+		switch trace.ops[i] {
+		case OpSet:
+			if ok {
+				item.next = next[i]
+				heap.Fix(&h, item.index)
+			} else {
+				insert(k, next[i])
+			}
+		case OpDelete:
+			if ok {
+				heap.Remove(&h, item.index)
+				delete(resident, k)
+			}
+		default:
+			if ok {
+				stats.HitCount++
+				item.next = next[i]
+				heap.Fix(&h, item.index)
+			} else {
+				stats.MissCount++
+				insert(k, next[i])
+			}
+			stats.record(int(trace.weights[i]), ok)
+		}
+		if opt.reportInterval > 0 && (i+1)%opt.reportInterval == 0 {
+			r.Report(stats, opt)
+		}
+	}
+	if opt.reportInterval == 0 {
+		r.Report(stats, opt)
+	}
+}
@@ -0,0 +1,82 @@
+package traces
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// mixedRequests returns n requests of zipf distributed keys with sets and
+// deletes.
+func mixedRequests(n int) []Request {
+	reqs := readRequests(NewZipfProvider(1.01, n), 0)
+	r := rand.New(rand.NewSource(1))
+	for i := range reqs {
+		switch x := r.Intn(10); {
+		case x < 2:
+			reqs[i].Op = OpSet
+		case x < 3:
+			reqs[i].Op = OpDelete
+		}
+	}
+	return reqs
+}
+
+func TestOPT(t *testing.T) {
+	// With capacity 2, OPT keeps 1 and 2 and does not admit 3 and 4.
+	keys := []uint64{1, 2, 3, 1, 2, 4, 1, 2, 3}
+	res := Simulate(newSliceProvider(keys...), optPolicy, 2, 0)
+	if res.Stats.HitCount != 4 || res.Stats.MissCount != 5 || res.Stats.EvictionCount != 3 {
+		t.Fatalf("unexpected stats: %+v", res.Stats)
+	}
+	res = Simulate(newSliceProvider(keys...), optPolicy, 2, 4)
+	if res.Stats.HitCount != 1 || res.Stats.MissCount != 3 {
+		t.Fatalf("unexpected stats: %+v", res.Stats)
+	}
+}
+
+func TestOPTNextUses(t *testing.T) {
+	p := sliceProvider{{Key: 1}, {Key: 2}, {Key: 1}, {Op: OpSet, Key: 2}, {Key: 2}}
+	next := readOPTTrace(&p, 0).nextUses()
+	// Never used again is the trace length.
+	want := []int{2, 5, 5, 4, 5}
+	for i := range want {
+		if next[i] != want[i] {
+			t.Fatalf("unexpected next uses: %v, want: %v", next, want)
+		}
+	}
+}
+
+func TestOPTUpperBound(t *testing.T) {
+	reqs := readRequests(NewZipfProvider(1.01, 20000), 0)
+	best := Simulate(requestsProvider(reqs), optPolicy, 100, 0)
+	for _, p := range policies {
+		res := Simulate(requestsProvider(reqs), p, 100, 0)
+		if res.Stats.HitCount > best.Stats.HitCount {
+			t.Fatalf("%s: hits %d exceed OPT hits %d", p, res.Stats.HitCount, best.Stats.HitCount)
+		}
+	}
+}
+
+func TestOPTOps(t *testing.T) {
+	p := sliceProvider{{Op: OpSet, Key: 1}, {Key: 1}}
+	res := Simulate(&p, optPolicy, 1, 0)
+	if res.Stats.HitCount != 1 || res.Stats.MissCount != 0 {
+		t.Fatalf("unexpected stats: %+v", res.Stats)
+	}
+	p = sliceProvider{{Key: 1}, {Op: OpDelete, Key: 1}, {Key: 1}}
+	res = Simulate(&p, optPolicy, 1, 0)
+	if res.Stats.HitCount != 0 || res.Stats.MissCount != 2 || res.Stats.EvictionCount != 0 {
+		t.Fatalf("unexpected stats: %+v", res.Stats)
+	}
+}
+
+func TestOPTUpperBoundOps(t *testing.T) {
+	reqs := mixedRequests(20000)
+	best := Simulate(requestsProvider(reqs), optPolicy, 100, 0)
+	for _, p := range policies {
+		res := Simulate(requestsProvider(reqs), p, 100, 0)
+		if res.Stats.HitCount > best.Stats.HitCount {
+			t.Fatalf("%s: hits %d exceed OPT hits %d", p, res.Stats.HitCount, best.Stats.HitCount)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package traces
+
+import (
+	"bufio"
+	"io"
+	"time"
+)
+
+// Number of requests read from a Provider at once.
+const requestBatchSize = 1024
+
+// Op is the operation of a request.
+type Op uint8
+
+// Operations of requests.
+const (
+	// OpGet reads the key and adds it to the cache on a miss.
+	OpGet Op = iota
+	// OpSet writes the key to the cache.
+	OpSet
+	// OpDelete removes the key from the cache.
+	OpDelete
+)
+
+// String returns name of the operation.
+func (op Op) String() string {
+	switch op {
+	case OpGet:
+		return "get"
+	case OpSet:
+		return "set"
+	case OpDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// Request is a cache request in a trace. Fields other than Key are zero
+// when they are not available in the trace.
+type Request struct {
+	Op Op
+	// Key is the requested key. Keys which are not integers in traces are
+	// hashed with FNV-1a.
+	Key uint64
+	// Size is the object size in bytes. It is used for byte hit rate, while
+	// cache capacity is in number of entries.
+	Size int
+	// TTL is the time to live of the object being set, which is applied
+	// from Time.
+	TTL time.Duration
+	// Time is when the request was made.
+	Time time.Time
+}
+
+// weight returns size of the request, or 1 if the size is unknown, so byte
+// hit rate equals object hit rate for traces without sizes.
+func (r *Request) weight() int {
+	if r.Size > 0 {
+		return r.Size
+	}
+	return 1
+}
+
+// Provider reads requests of a trace.
+type Provider interface {
+	// Read reads up to len(reqs) requests into reqs. It returns the number
+	// of requests read and io.EOF when there are no more requests.
+	// Like io.Reader, it may return a non-nil error with n > 0.
+	Read(reqs []Request) (n int, err error)
+}
+
+// replay calls fn for each of at most maxItems requests, or all requests if
+// maxItems is not positive, of p.
+func replay(p Provider, maxItems int, fn func(Request)) {
+	reqs := make([]Request, requestBatchSize)
+	total := 0
+	for {
+		if maxItems > 0 && maxItems-total < len(reqs) {
+			reqs = reqs[:maxItems-total]
+		}
+		if len(reqs) == 0 {
+			return
+		}
+		n, err := p.Read(reqs)
+		for _, r := range reqs[:n] {
+			fn(r)
+		}
+		total += n
+		if err != nil {
+			return
+		}
+	}
+}
+
+// lineProvider reads requests from lines of text.
+type lineProvider struct {
+	r *bufio.Reader
+	// parse returns the request of a line and false if the line is not a request.
+	parse func(b []byte) (Request, bool)
+}
+
+// newLineProvider returns a lineProvider of get requests of keys parsed from
+// lines with the given function.
+func newLineProvider(r io.Reader, parse func([]byte) (uint64, bool)) *lineProvider {
+	return &lineProvider{
+		r: bufio.NewReader(r),
+		parse: func(b []byte) (Request, bool) {
+			k, ok := parse(b)
+			return Request{Key: k}, ok
+		},
+	}
+}
+
+func (p *lineProvider) Read(reqs []Request) (int, error) {
+	n := 0
+	for n < len(reqs) {
+		b, err := p.r.ReadBytes('\n')
+		if err != nil {
+			return n, err
+		}
+		if req, ok := p.parse(b); ok {
+			reqs[n] = req
+			n++
+		}
+	}
+	return n, nil
+}
+
+// hashBytes returns the FNV-1a hash of b.
+func hashBytes(b []byte) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	h := uint64(offset64)
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= prime64
+	}
+	return h
+}
@@ -0,0 +1,89 @@
+package traces
+
+import (
+	"hash/fnv"
+	"io"
+	"strings"
+	"testing"
+)
+
+// sliceProvider provides requests in the slice.
+type sliceProvider []Request
+
+func newSliceProvider(keys ...uint64) *sliceProvider {
+	p := make(sliceProvider, len(keys))
+	for i, k := range keys {
+		p[i].Key = k
+	}
+	return &p
+}
+
+// readRequests reads at most maxItems requests, or all requests if maxItems
+// is not positive, from p.
+func readRequests(p Provider, maxItems int) []Request {
+	var reqs []Request
+	replay(p, maxItems, func(r Request) {
+		reqs = append(reqs, r)
+	})
+	return reqs
+}
+
+func requestsProvider(reqs []Request) *sliceProvider {
+	p := sliceProvider(reqs)
+	return &p
+}
+
+func (p *sliceProvider) Read(reqs []Request) (int, error) {
+	if len(*p) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(reqs, *p)
+	*p = (*p)[n:]
+	return n, nil
+}
+
+func TestReplay(t *testing.T) {
+	keys := make([]uint64, 3*requestBatchSize)
+	for i := range keys {
+		keys[i] = uint64(i)
+	}
+	for _, max := range []int{0, 1, requestBatchSize + 1, len(keys)} {
+		n := 0
+		replay(newSliceProvider(keys...), max, func(r Request) {
+			if r.Key != uint64(n) {
+				t.Fatalf("unexpected request %d: %+v", n, r)
+			}
+			n++
+		})
+		if (max > 0 && n != max) || (max == 0 && n != len(keys)) {
+			t.Fatalf("unexpected number of requests with max %d: %d", max, n)
+		}
+	}
+}
+
+func TestLineProvider(t *testing.T) {
+	// Lines longer than the read buffer are not skipped.
+	long := strings.Repeat("x", 8192)
+	r := strings.NewReader("1\n" + long + "\n\n2\n3")
+	p := newLineProvider(r, func(b []byte) (uint64, bool) {
+		if len(b) < 2 {
+			return 0, false
+		}
+		return hashBytes(b[:len(b)-1]), true
+	})
+	reqs := readRequests(p, 0)
+	if len(reqs) != 3 || reqs[0].Key != hashBytes([]byte("1")) ||
+		reqs[1].Key != hashBytes([]byte(long)) || reqs[2].Key != hashBytes([]byte("2")) {
+		t.Fatalf("unexpected requests: %+v", reqs)
+	}
+}
+
+func TestHashBytes(t *testing.T) {
+	for _, s := range []string{"", "a", "/wiki/Main_Page"} {
+		h := fnv.New64a()
+		h.Write([]byte(s))
+		if hashBytes([]byte(s)) != h.Sum64() {
+			t.Fatalf("unexpected hash of %q", s)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package traces
+
+import (
+	"io"
+
+	"github.com/goburrow/cache"
+)
+
+type recordedProvider struct {
+	r *cache.TraceReader
+}
+
+// NewRecordedProvider returns a Provider which items are operations recorded
+// by cache.WithTraceRecorder. GetIfPresent and Get are mapped to OpGet, Put to
+// OpSet and Invalidate to OpDelete.
+func NewRecordedProvider(r io.Reader) Provider {
+	return &recordedProvider{
+		r: cache.NewTraceReader(r),
+	}
+}
+
+func (p *recordedProvider) Read(reqs []Request) (int, error) {
+	n := 0
+	for n < len(reqs) {
+		rec, err := p.r.Read()
+		if err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return n, err
+		}
+		var op Op
+		switch rec.Op {
+		case cache.TraceGetIfPresent, cache.TraceGet:
+			op = OpGet
+		case cache.TracePut:
+			op = OpSet
+		case cache.TraceInvalidate:
+			op = OpDelete
+		default:
+			continue
+		}
+		reqs[n] = Request{Op: op, Key: rec.Hash, Time: rec.Time}
+		n++
+	}
+	return n, nil
+}
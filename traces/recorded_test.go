@@ -0,0 +1,28 @@
+package traces
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/goburrow/cache"
+)
+
+func TestRecordedProvider(t *testing.T) {
+	var b bytes.Buffer
+	c := cache.New(cache.WithTraceRecorder(&b, 1))
+	for i := 0; i < 100; i++ {
+		if _, ok := c.GetIfPresent(i % 10); !ok {
+			c.Put(i%10, i)
+		}
+	}
+	c.Close()
+
+	p, err := NewProvider("recorded", &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := Simulate(p, "lru", 10, 0)
+	if res.Stats.RequestCount() != 100 || res.Stats.HitCount != 90 {
+		t.Fatalf("unexpected stats: %+v", res.Stats)
+	}
+}
@@ -1,19 +1,40 @@
 package traces
 
 import (
-	"context"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/goburrow/cache"
 )
 
 type Reporter interface {
-	Report(cache.Stats, options)
+	Report(Stats, options)
 }
 
-type Provider interface {
-	Provide(ctx context.Context, keys chan<- interface{})
+// Stats is statistics of replaying a trace. Only get requests are counted.
+type Stats struct {
+	cache.Stats
+	// RequestBytes is the total size of requested objects.
+	RequestBytes uint64
+	// HitBytes is the total size of objects returned by cache hits.
+	HitBytes uint64
+}
+
+// ByteHitRate returns the ratio of requested bytes which were hits.
+func (s *Stats) ByteHitRate() float64 {
+	if s.RequestBytes == 0 {
+		return 1.0
+	}
+	return float64(s.HitBytes) / float64(s.RequestBytes)
+}
+
+// record records a get request of the given size.
+func (s *Stats) record(size int, hit bool) {
+	s.RequestBytes += uint64(size)
+	if hit {
+		s.HitBytes += uint64(size)
+	}
 }
 
 type reporter struct {
@@ -25,14 +46,14 @@ func NewReporter(w io.Writer) Reporter {
 	return &reporter{w: w}
 }
 
-func (r *reporter) Report(st cache.Stats, opt options) {
+func (r *reporter) Report(st Stats, opt options) {
 	if !r.headerPrinted {
-		fmt.Fprintf(r.w, "Requets,Hits,HitRate,Evictions,CacheSize\n")
+		fmt.Fprintf(r.w, "Requets,Hits,HitRate,Evictions,CacheSize,ByteHitRate\n")
 		r.headerPrinted = true
 	}
-	fmt.Fprintf(r.w, "%d,%d,%.04f,%d,%d\n",
+	fmt.Fprintf(r.w, "%d,%d,%.04f,%d,%d,%.04f\n",
 		st.RequestCount(), st.HitCount, st.HitRate(), st.EvictionCount,
-		opt.cacheSize)
+		opt.cacheSize, st.ByteHitRate())
 }
 
 type options struct {
@@ -46,39 +67,66 @@ var policies = []string{
 	"lru",
 	"slru",
 	"tinylfu",
+	"adaptive",
+	"arc",
+	"lirs",
+	"s3fifo",
+	"sieve",
+	optPolicy,
 }
 
+// benchmarkCache replays requests from p through a cache of opt.cacheSize
+// entries. Object sizes are only used to compute byte hit rate and by
+// size-aware policies such as gdsf. Keys set with TTL expire after it.
 func benchmarkCache(p Provider, r Reporter, opt options) {
-	c := cache.New(cache.WithMaximumSize(opt.cacheSize), cache.WithPolicy(opt.policy))
-	defer c.Close()
-
-	keys := make(chan interface{}, 100)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	if opt.policy == optPolicy {
+		benchmarkOPT(p, r, opt)
+		return
+	}
+	c := cache.NewSimulator(cache.WithMaximumSize(opt.cacheSize), cache.WithPolicy(opt.policy),
+		cache.WithWeigher(sizeWeigher))
 
-	go p.Provide(ctx, keys)
-	stats := cache.Stats{}
+	stats := Stats{}
+	// expires is the expiration time of keys set with TTL.
+	expires := make(map[uint64]time.Time)
 	i := 0
-	for {
-		if opt.maxItems > 0 && i >= opt.maxItems {
-			break
-		}
-		k, ok := <-keys
-		if !ok {
-			break
-		}
-		_, ok = c.GetIfPresent(k)
-		if !ok {
-			c.Put(k, k)
+	replay(p, opt.maxItems, func(req Request) {
+		switch req.Op {
+		case OpSet:
+			c.Put(req.Key, req.weight())
+			if req.TTL > 0 && !req.Time.IsZero() {
+				expires[req.Key] = req.Time.Add(req.TTL)
+			} else {
+				delete(expires, req.Key)
+			}
+		case OpDelete:
+			c.Invalidate(req.Key)
+			delete(expires, req.Key)
+		default:
+			if exp, ok := expires[req.Key]; ok && !req.Time.Before(exp) {
+				c.Invalidate(req.Key)
+				delete(expires, req.Key)
+			}
+			_, ok := c.Get(req.Key)
+			if !ok {
+				c.Put(req.Key, req.weight())
+				delete(expires, req.Key)
+			}
+			stats.record(req.weight(), ok)
 		}
 		i++
 		if opt.reportInterval > 0 && i%opt.reportInterval == 0 {
-			c.Stats(&stats)
+			c.Stats(&stats.Stats)
 			r.Report(stats, opt)
 		}
-	}
+	})
 	if opt.reportInterval == 0 {
-		c.Stats(&stats)
+		c.Stats(&stats.Stats)
 		r.Report(stats, opt)
 	}
 }
+
+// sizeWeigher returns the object size stored as the cache value.
+func sizeWeigher(k cache.Key, v cache.Value) int {
+	return v.(int)
+}
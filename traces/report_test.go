@@ -4,6 +4,7 @@ import (
 	"io"
 	"os"
 	"testing"
+	"time"
 )
 
 func testRequest(t *testing.T, newProvider func(io.Reader) Provider, opt options, traceFiles string, reportFile string) {
@@ -45,3 +46,39 @@ func testSize(t *testing.T, newProvider func(io.Reader) Provider, opt options, t
 		opt.cacheSize += opt.cacheSize
 	}
 }
+
+func TestBenchmarkCacheOps(t *testing.T) {
+	p := sliceProvider{
+		{Key: 1, Size: 100},
+		{Key: 1, Size: 100},
+		{Key: 2, Size: 10},
+		{Op: OpDelete, Key: 1},
+		{Key: 1, Size: 100},
+		{Op: OpSet, Key: 3, Size: 5},
+		{Key: 3, Size: 5},
+		{Key: 2, Size: 10},
+	}
+	res := Simulate(&p, "lru", 10, 0)
+	st := res.Stats
+	if st.HitCount != 3 || st.MissCount != 3 || st.RequestBytes != 325 || st.HitBytes != 115 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+	if st.ByteHitRate() == st.HitRate() {
+		t.Fatalf("unexpected byte hit rate: %v", st.ByteHitRate())
+	}
+}
+
+func TestBenchmarkCacheTTL(t *testing.T) {
+	now := time.Unix(100, 0)
+	p := sliceProvider{
+		{Op: OpSet, Key: 1, TTL: 10 * time.Second, Time: now},
+		{Key: 1, Time: now.Add(5 * time.Second)},
+		{Key: 1, Time: now.Add(10 * time.Second)},
+		// Loaded again without TTL.
+		{Key: 1, Time: now.Add(30 * time.Second)},
+	}
+	res := Simulate(&p, "lru", 10, 0)
+	if res.Stats.HitCount != 2 || res.Stats.MissCount != 1 {
+		t.Fatalf("unexpected stats: %+v", res.Stats)
+	}
+}
@@ -0,0 +1,90 @@
+package traces
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Formats is the list of trace formats supported by NewProvider.
+var Formats = []string{
+	"address",
+	"cache2k",
+	"recorded",
+	"storage",
+	"twitter",
+	"wikipedia",
+	"youtube",
+}
+
+// NewProvider returns a Provider reading traces of the given format from r.
+func NewProvider(format string, r io.Reader) (Provider, error) {
+	switch format {
+	case "address":
+		return NewAddressProvider(r), nil
+	case "cache2k":
+		return NewCache2kProvider(r), nil
+	case "recorded":
+		return NewRecordedProvider(r), nil
+	case "storage":
+		return NewStorageProvider(r), nil
+	case "twitter":
+		return NewTwitterProvider(r), nil
+	case "wikipedia":
+		return NewWikipediaProvider(r), nil
+	case "youtube":
+		return NewYoutubeProvider(r), nil
+	default:
+		return nil, fmt.Errorf("unsupported trace format %s", format)
+	}
+}
+
+// Policies returns names of cache policies used in reports.
+func Policies() []string {
+	return append([]string(nil), policies...)
+}
+
+// Result is the result of replaying traces through a cache.
+type Result struct {
+	Policy    string
+	CacheSize int
+	Stats     Stats
+	Duration  time.Duration
+}
+
+// Throughput returns number of requests per second.
+func (r *Result) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Stats.RequestCount()) / r.Duration.Seconds()
+}
+
+// Simulate replays at most maxItems requests, or all requests if maxItems is
+// not positive, from p through a cache of the given policy and size.
+// The cache size is the maximum number of entries, regardless of object sizes.
+func Simulate(p Provider, policy string, cacheSize, maxItems int) Result {
+	opt := options{
+		policy:    policy,
+		cacheSize: cacheSize,
+		maxItems:  maxItems,
+	}
+	r := &statsReporter{}
+	start := time.Now()
+	benchmarkCache(p, r, opt)
+	return Result{
+		Policy:    policy,
+		CacheSize: cacheSize,
+		Stats:     r.stats,
+		Duration:  time.Since(start),
+	}
+}
+
+// statsReporter keeps the last reported stats.
+type statsReporter struct {
+	stats Stats
+}
+
+func (r *statsReporter) Report(st Stats, opt options) {
+	r.stats = st
+}
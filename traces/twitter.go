@@ -0,0 +1,98 @@
+package traces
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"time"
+)
+
+// Number of fields in a line of Twitter traces.
+const twitterFields = 7
+
+// NewTwitterProvider returns a Provider with items are from
+// Twitter's production cache traces
+// (https://github.com/twitter/cache-trace).
+// Each line contains timestamp, anonymized key, key size, value size,
+// client id, operation and TTL. Keys may contain commas, so other fields are
+// split from both ends of the line. Reads are mapped to OpGet, writes to
+// OpSet and deletes to OpDelete. The request size is the sum of key and value
+// sizes.
+// As an approximation, conditional writes (add, replace, cas, append,
+// prepend, incr and decr) are mapped to OpSet although twemcache only applies
+// them depending on whether the key exists.
+func NewTwitterProvider(r io.Reader) Provider {
+	return &lineProvider{
+		r:     bufio.NewReader(r),
+		parse: parseTwitter,
+	}
+}
+
+func parseTwitter(b []byte) (Request, bool) {
+	var fields [twitterFields][]byte
+	b = bytes.TrimRight(b, "\r\n")
+	idx := bytes.IndexByte(b, ',')
+	if idx < 0 {
+		return Request{}, false
+	}
+	fields[0] = b[:idx]
+	b = b[idx+1:]
+	// The key is everything before the last fields.
+	for i := twitterFields - 1; i > 1; i-- {
+		idx = bytes.LastIndexByte(b, ',')
+		if idx < 0 {
+			return Request{}, false
+		}
+		fields[i] = b[idx+1:]
+		b = b[:idx]
+	}
+	fields[1] = b
+
+	op, ok := twitterOp(fields[5])
+	if !ok || len(fields[1]) == 0 {
+		return Request{}, false
+	}
+	ts, ok1 := parseInt(fields[0])
+	keySize, ok2 := parseInt(fields[2])
+	valueSize, ok3 := parseInt(fields[3])
+	ttl, ok4 := parseInt(fields[6])
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return Request{}, false
+	}
+	return Request{
+		Op:   op,
+		Key:  hashBytes(fields[1]),
+		Size: int(keySize + valueSize),
+		TTL:  time.Duration(ttl) * time.Second,
+		Time: time.Unix(ts, 0),
+	}, true
+}
+
+// twitterOp maps twemcache commands to request operations.
+func twitterOp(b []byte) (Op, bool) {
+	switch string(b) {
+	case "get", "gets":
+		return OpGet, true
+	case "set", "add", "replace", "cas", "append", "prepend", "incr", "decr":
+		return OpSet, true
+	case "delete":
+		return OpDelete, true
+	default:
+		return 0, false
+	}
+}
+
+// parseInt parses a non-negative decimal integer.
+func parseInt(b []byte) (int64, bool) {
+	if len(b) == 0 {
+		return 0, false
+	}
+	var n int64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int64(c-'0')
+	}
+	return n, true
+}
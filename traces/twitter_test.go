@@ -0,0 +1,75 @@
+package traces
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTwitterProvider(t *testing.T) {
+	trace := `0,key-a,5,100,1,get,0
+0,key-a,5,100,1,set,3600
+1,key-b,5,20,2,gets,0
+bad line
+1,key-c,5,0,2,unknown,0
+2,key-a,5,0,1,delete,0
+2,key-a,5,100,1,get,0
+3,key,d,7,10,3,get,0
+`
+	reqs := readRequests(NewTwitterProvider(strings.NewReader(trace)), 0)
+	want := []Request{
+		{Op: OpGet, Key: hashBytes([]byte("key-a")), Size: 105, Time: time.Unix(0, 0)},
+		{Op: OpSet, Key: hashBytes([]byte("key-a")), Size: 105, TTL: time.Hour, Time: time.Unix(0, 0)},
+		{Op: OpGet, Key: hashBytes([]byte("key-b")), Size: 25, Time: time.Unix(1, 0)},
+		{Op: OpDelete, Key: hashBytes([]byte("key-a")), Size: 5, Time: time.Unix(2, 0)},
+		{Op: OpGet, Key: hashBytes([]byte("key-a")), Size: 105, Time: time.Unix(2, 0)},
+		{Op: OpGet, Key: hashBytes([]byte("key,d")), Size: 17, Time: time.Unix(3, 0)},
+	}
+	if len(reqs) != len(want) {
+		t.Fatalf("unexpected requests: %+v", reqs)
+	}
+	for i := range want {
+		if reqs[i] != want[i] {
+			t.Fatalf("unexpected request %d: %+v, want: %+v", i, reqs[i], want[i])
+		}
+	}
+
+	res := Simulate(NewTwitterProvider(strings.NewReader(trace)), "lru", 10, 0)
+	// The last get of key-a is a miss as it has been deleted.
+	if res.Stats.HitCount != 0 || res.Stats.MissCount != 4 {
+		t.Fatalf("unexpected stats: %+v", res.Stats)
+	}
+}
+
+func TestRequestTwitter(t *testing.T) {
+	for _, p := range policies {
+		p := p
+		t.Run(p, func(t *testing.T) {
+			t.Parallel()
+			opt := options{
+				policy:         p,
+				cacheSize:      512,
+				reportInterval: 40000,
+				maxItems:       4000000,
+			}
+			testRequest(t, NewTwitterProvider, opt,
+				"cluster*.sort.*", "request_twitter-"+p+".txt")
+		})
+	}
+}
+
+func TestSizeTwitter(t *testing.T) {
+	for _, p := range policies {
+		p := p
+		t.Run(p, func(t *testing.T) {
+			t.Parallel()
+			opt := options{
+				policy:    p,
+				cacheSize: 250,
+				maxItems:  1000000,
+			}
+			testSize(t, NewTwitterProvider, opt,
+				"cluster*.sort.*", "size_twitter-"+p+".txt")
+		})
+	}
+}
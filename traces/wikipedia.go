@@ -1,45 +1,19 @@
 package traces
 
 import (
-	"bufio"
 	"bytes"
-	"context"
 	"io"
 )
 
-type wikipediaProvider struct {
-	r *bufio.Reader
-}
-
 func NewWikipediaProvider(r io.Reader) Provider {
-	return &wikipediaProvider{
-		r: bufio.NewReader(r),
-	}
-}
-
-func (p *wikipediaProvider) Provide(ctx context.Context, keys chan<- interface{}) {
-	defer close(keys)
-	for {
-		b, err := p.r.ReadBytes('\n')
-		if err != nil {
-			return
-		}
-		v := p.parse(b)
-		if v != "" {
-			select {
-			case <-ctx.Done():
-				return
-			case keys <- v:
-			}
-		}
-	}
+	return newLineProvider(r, parseWikipedia)
 }
 
-func (p *wikipediaProvider) parse(b []byte) string {
+func parseWikipedia(b []byte) (uint64, bool) {
 	// Get url
 	idx := bytes.Index(b, []byte("http://"))
 	if idx < 0 {
-		return ""
+		return 0, false
 	}
 	b = b[idx+len("http://"):]
 	// Get path
@@ -52,5 +26,5 @@ func (p *wikipediaProvider) parse(b []byte) string {
 	if idx > 0 {
 		b = b[:idx]
 	}
-	return string(b)
+	return hashBytes(b), true
 }
@@ -1,7 +1,7 @@
 package traces
 
 import (
-	"context"
+	"io"
 	"math/rand"
 )
 
@@ -21,14 +21,16 @@ func NewZipfProvider(s float64, num int) Provider {
 	}
 }
 
-func (p *zipfProvider) Provide(ctx context.Context, keys chan<- interface{}) {
-	defer close(keys)
-	for i := 0; i < p.n; i++ {
-		v := p.r.Uint64()
-		select {
-		case <-ctx.Done():
-			return
-		case keys <- v:
-		}
+func (p *zipfProvider) Read(reqs []Request) (int, error) {
+	if p.n <= 0 {
+		return 0, io.EOF
 	}
+	if len(reqs) > p.n {
+		reqs = reqs[:p.n]
+	}
+	for i := range reqs {
+		reqs[i] = Request{Key: p.r.Uint64()}
+	}
+	p.n -= len(reqs)
+	return len(reqs), nil
 }